@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
@@ -20,12 +21,16 @@ import (
 	"github.com/itam-misis/itam-api/internal/clubs"
 	"github.com/itam-misis/itam-api/internal/config"
 	"github.com/itam-misis/itam-api/internal/database"
+	"github.com/itam-misis/itam-api/internal/events"
+	"github.com/itam-misis/itam-api/internal/hackathons"
 	"github.com/itam-misis/itam-api/internal/logs"
 	"github.com/itam-misis/itam-api/internal/middleware"
 	"github.com/itam-misis/itam-api/internal/news"
 	"github.com/itam-misis/itam-api/internal/partners"
 	"github.com/itam-misis/itam-api/internal/projects"
 	"github.com/itam-misis/itam-api/internal/stats"
+	"github.com/itam-misis/itam-api/internal/storage"
+	"github.com/itam-misis/itam-api/internal/tags"
 	"github.com/itam-misis/itam-api/internal/team"
 	"github.com/itam-misis/itam-api/internal/telegram"
 	"github.com/itam-misis/itam-api/internal/upload"
@@ -40,20 +45,23 @@ type App struct {
 	router *chi.Mux
 
 	// Services
-	authService     *auth.Service
-	usersService    *users.Service
-	auditService    *audit.Service
-	winsService     *wins.Service
-	projectsService *projects.Service
-	teamService     *team.Service
-	newsService     *news.Service
-	partnersService *partners.Service
-	clubsService    *clubs.Service
-	blogService     *blog.Service
-	statsService    *stats.Service
-	uploadService   *upload.Service
-	cacheService    *cache.Service
-	telegramService *telegram.Service
+	authService       *auth.Service
+	usersService      *users.Service
+	auditService      *audit.Service
+	winsService       *wins.Service
+	hackathonsService *hackathons.Service
+	tagsService       *tags.Service
+	eventsService     *events.Service
+	projectsService   *projects.Service
+	teamService       *team.Service
+	newsService       *news.Service
+	partnersService   *partners.Service
+	clubsService      *clubs.Service
+	blogService       *blog.Service
+	statsService      *stats.Service
+	uploadService     *upload.Service
+	cacheService      *cache.Service
+	telegramService   *telegram.Service
 }
 
 func main() {
@@ -95,6 +103,9 @@ func main() {
 	authService := auth.NewService(db.Pool, cfg.JWT.Secret, cfg.JWT.Expiry)
 	usersService := users.NewService(db.Pool)
 	winsService := wins.NewService(db.Pool, auditService)
+	hackathonsService := hackathons.NewService(db.Pool, auditService)
+	tagsService := tags.NewService(db.Pool, auditService)
+	eventsService := events.NewService(db.Pool, auditService, winsService)
 	projectsService := projects.NewService(db.Pool, auditService)
 	teamService := team.NewService(db.Pool, auditService)
 	newsService := news.NewService(db.Pool, auditService)
@@ -102,33 +113,65 @@ func main() {
 	clubsService := clubs.NewService(db.Pool, auditService)
 	blogService := blog.NewService(db.Pool, auditService)
 	statsService := stats.NewService(db.Pool, auditService)
-	uploadService := upload.NewService(upload.Config{
-		UploadPath: cfg.Upload.Path,
-		MaxSize:    cfg.Upload.MaxSize,
-		BaseURL:    "/uploads",
+	uploadStorage, err := storage.New(ctx, storage.Config{
+		Driver:    cfg.Storage.Driver,
+		LocalPath: cfg.Upload.Path,
+		S3: storage.S3Config{
+			Endpoint:       cfg.Storage.S3Endpoint,
+			Bucket:         cfg.Storage.S3Bucket,
+			AccessKey:      cfg.Storage.S3AccessKey,
+			SecretKey:      cfg.Storage.S3SecretKey,
+			Region:         cfg.Storage.S3Region,
+			UseSSL:         cfg.Storage.S3UseSSL,
+			PublicEndpoint: cfg.Storage.S3PublicEndpoint,
+			PublicUseSSL:   cfg.Storage.S3PublicUseSSL,
+		},
 	})
-	cacheService := cache.NewService(redisDB.Client)
+	if err != nil {
+		slog.Error("failed to initialize upload storage", "error", err)
+		os.Exit(1)
+	}
+	uploadService := upload.NewService(db.Pool, auditService, uploadStorage, upload.Config{
+		MaxSize:      cfg.Upload.MaxSize,
+		MaxVideoSize: cfg.Upload.MaxVideoSize,
+		BaseURL:      cfg.Upload.BaseURL,
+		Quotas:       cfg.Upload.Quotas,
+		TotalQuota:   cfg.Upload.TotalQuota,
+	})
+	cacheService := cache.NewService(redisDB.Client, auditService)
 	telegramService := telegram.NewService(redisDB.Client)
 
+	// Landing stats can show the Telegram subscriber count collected by the bot
+	statsService.RegisterSource("telegram_subscribers", func(ctx context.Context, _ stats.SourceParams) (float64, error) {
+		channel, err := telegramService.GetStats(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return float64(channel.SubscribersCount), nil
+	})
+
 	// Initialize app
 	app := &App{
-		config:          cfg,
-		db:              db,
-		redis:           redisDB,
-		authService:     authService,
-		usersService:    usersService,
-		auditService:    auditService,
-		winsService:     winsService,
-		projectsService: projectsService,
-		teamService:     teamService,
-		newsService:     newsService,
-		partnersService: partnersService,
-		clubsService:    clubsService,
-		blogService:     blogService,
-		statsService:    statsService,
-		uploadService:   uploadService,
-		cacheService:    cacheService,
-		telegramService: telegramService,
+		config:            cfg,
+		db:                db,
+		redis:             redisDB,
+		authService:       authService,
+		usersService:      usersService,
+		auditService:      auditService,
+		winsService:       winsService,
+		hackathonsService: hackathonsService,
+		tagsService:       tagsService,
+		eventsService:     eventsService,
+		projectsService:   projectsService,
+		teamService:       teamService,
+		newsService:       newsService,
+		partnersService:   partnersService,
+		clubsService:      clubsService,
+		blogService:       blogService,
+		statsService:      statsService,
+		uploadService:     uploadService,
+		cacheService:      cacheService,
+		telegramService:   telegramService,
 	}
 
 	// Seed initial admin if needed
@@ -140,6 +183,29 @@ func main() {
 	// Setup router
 	app.setupRouter()
 
+	// Background jobs stop on shutdown
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	// Warm public caches on startup and after every invalidation
+	warmer := cache.NewWarmer(app.router, app.warmupTargets, cache.WarmerConfig{})
+	cacheService.OnInvalidate(warmer.Trigger)
+	go warmer.Run(bgCtx)
+
+	// Keep stored values of computed stats current, as their fallback
+	go statsService.RunStoreJob(bgCtx, 15*time.Minute)
+
+	// Classify wins stored before placements existed
+	go winsService.ClassifyPending(bgCtx)
+
+	// Remove expired upload sessions and abandoned incoming files, whatever the orphan mode
+	go uploadService.RunSessionJob(bgCtx, time.Hour)
+
+	// Report or delete uploads no entity references
+	if cfg.Upload.OrphanCleanup != config.OrphanCleanupOff {
+		go uploadService.RunOrphanJob(bgCtx, 24*time.Hour, cfg.Upload.OrphanCleanup == config.OrphanCleanupDelete)
+	}
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -212,11 +278,15 @@ func (a *App) setupRouter() {
 	r.Use(chimiddleware.Recoverer)
 	r.Use(chimiddleware.Timeout(60 * time.Second))
 	r.Use(middleware.CORS)
+	r.Use(middleware.Compress(5))
 
 	// Initialize handlers
 	authHandler := auth.NewHandler(a.authService)
 	usersHandler := users.NewHandler(a.usersService)
 	winsHandler := wins.NewHandler(a.winsService)
+	hackathonsHandler := hackathons.NewHandler(a.hackathonsService)
+	tagsHandler := tags.NewHandler(a.tagsService)
+	eventsHandler := events.NewHandler(a.eventsService)
 	projectsHandler := projects.NewHandler(a.projectsService)
 	teamHandler := team.NewHandler(a.teamService)
 	newsHandler := news.NewHandler(a.newsService)
@@ -225,6 +295,7 @@ func (a *App) setupRouter() {
 	blogHandler := blog.NewHandler(a.blogService)
 	statsHandler := stats.NewHandler(a.statsService)
 	logsHandler := logs.NewHandler(a.auditService)
+	cacheHandler := cache.NewHandler(a.cacheService)
 	uploadHandler := upload.NewHandler(a.uploadService)
 	telegramHandler := telegram.NewHandler(a.telegramService)
 
@@ -245,9 +316,10 @@ func (a *App) setupRouter() {
 			})
 		})
 
-		// Protected routes
+		// Protected routes; content writes invalidate the public caches they affect
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.Auth(a.authService))
+			c := a.cacheService
 
 			// Users (admin only)
 			r.Route("/users", func(r chi.Router) {
@@ -261,18 +333,49 @@ func (a *App) setupRouter() {
 
 			// Wins
 			r.Route("/wins", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateWins, c.InvalidateProjects, c.InvalidateStats))
 				r.Get("/", winsHandler.List)
 				r.Post("/", winsHandler.Create)
 				r.Get("/years", winsHandler.GetYears)
 				r.Get("/stats", winsHandler.GetStats)
+				r.Get("/analytics", winsHandler.GetAnalytics)
+				r.Get("/export", winsHandler.Export)
 				r.Post("/import", winsHandler.Import)
+				r.Post("/import/{id}/commit", winsHandler.CommitImport)
+				r.Post("/bulk", winsHandler.Bulk)
+				r.Get("/rules", winsHandler.ListRules)
+				r.Post("/rules", winsHandler.CreateRule)
+				r.Put("/rules/{id}", winsHandler.UpdateRule)
+				r.Delete("/rules/{id}", winsHandler.DeleteRule)
 				r.Get("/{id}", winsHandler.Get)
 				r.Put("/{id}", winsHandler.Update)
 				r.Delete("/{id}", winsHandler.Delete)
 			})
 
+			// Hackathons
+			r.Route("/hackathons", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateWins, c.InvalidateStats))
+				r.Get("/", hackathonsHandler.List)
+				r.Post("/", hackathonsHandler.Create)
+				r.Get("/{id}", hackathonsHandler.Get)
+				r.Put("/{id}", hackathonsHandler.Update)
+				r.Delete("/{id}", hackathonsHandler.Delete)
+			})
+
+			// Events
+			r.Route("/events", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateEvents, c.InvalidateWins, c.InvalidateStats))
+				r.Get("/", eventsHandler.List)
+				r.Post("/", eventsHandler.Create)
+				r.Get("/{id}", eventsHandler.Get)
+				r.Put("/{id}", eventsHandler.Update)
+				r.Delete("/{id}", eventsHandler.Delete)
+				r.Post("/{id}/win", eventsHandler.ConvertToWin)
+			})
+
 			// Projects
 			r.Route("/projects", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateProjects, c.InvalidateStats))
 				r.Get("/", projectsHandler.List)
 				r.Post("/", projectsHandler.Create)
 				r.Get("/tags", projectsHandler.ListTags)
@@ -282,8 +385,19 @@ func (a *App) setupRouter() {
 				r.Delete("/{id}", projectsHandler.Delete)
 			})
 
+			// Tags of projects and events
+			r.Route("/tags", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateProjects, c.InvalidateEvents))
+				r.Get("/", tagsHandler.List)
+				r.Post("/merge", tagsHandler.Merge)
+				r.Delete("/unused", tagsHandler.DeleteUnused)
+				r.Put("/{id}", tagsHandler.Rename)
+				r.Delete("/{id}", tagsHandler.Delete)
+			})
+
 			// Team
 			r.Route("/team", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateTeam, c.InvalidateWins, c.InvalidateProjects, c.InvalidateStats))
 				r.Get("/", teamHandler.List)
 				r.Post("/", teamHandler.Create)
 				r.Get("/{id}", teamHandler.Get)
@@ -293,6 +407,7 @@ func (a *App) setupRouter() {
 
 			// News
 			r.Route("/news", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateNews))
 				r.Get("/", newsHandler.List)
 				r.Post("/", newsHandler.Create)
 				r.Get("/{id}", newsHandler.Get)
@@ -302,6 +417,7 @@ func (a *App) setupRouter() {
 
 			// Partners
 			r.Route("/partners", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidatePartners, c.InvalidateStats))
 				r.Get("/", partnersHandler.List)
 				r.Post("/", partnersHandler.Create)
 				r.Put("/reorder", partnersHandler.Reorder)
@@ -312,6 +428,7 @@ func (a *App) setupRouter() {
 
 			// Clubs
 			r.Route("/clubs", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateClubs, c.InvalidateTeam, c.InvalidateEvents, c.InvalidateStats))
 				r.Get("/", clubsHandler.List)
 				r.Post("/", clubsHandler.Create)
 				r.Get("/{id}", clubsHandler.Get)
@@ -321,6 +438,7 @@ func (a *App) setupRouter() {
 
 			// Blog
 			r.Route("/blog", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateBlog))
 				r.Get("/", blogHandler.List)
 				r.Post("/", blogHandler.Create)
 				r.Get("/{id}", blogHandler.Get)
@@ -330,7 +448,9 @@ func (a *App) setupRouter() {
 
 			// Stats
 			r.Route("/stats", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateStats))
 				r.Get("/", statsHandler.List)
+				r.Get("/sources", statsHandler.Sources)
 				r.Put("/{key}", statsHandler.Update)
 			})
 
@@ -340,13 +460,43 @@ func (a *App) setupRouter() {
 				r.Get("/", logsHandler.List)
 			})
 
+			// Cache management (admin only)
+			r.Route("/cache", func(r chi.Router) {
+				r.Use(middleware.RequireAdmin)
+				r.Get("/", cacheHandler.List)
+				r.Post("/purge", cacheHandler.Purge)
+			})
+
 			// Upload
 			r.Route("/upload", func(r chi.Router) {
 				r.Post("/image", uploadHandler.UploadImage)
 				r.Post("/svg", uploadHandler.UploadSVG)
+				r.Post("/presign", uploadHandler.Presign)
+				r.Post("/complete", uploadHandler.Complete)
+				r.Get("/usage", uploadHandler.Usage)
+				r.Route("/sessions", func(r chi.Router) {
+					r.Post("/", uploadHandler.CreateSession)
+					r.Get("/{id}", uploadHandler.GetSession)
+					r.Put("/{id}/chunks/{index}", uploadHandler.PutChunk)
+					r.Post("/{id}/complete", uploadHandler.CompleteSession)
+					r.Delete("/{id}", uploadHandler.AbortSession)
+				})
 				r.Delete("/{filename}", uploadHandler.Delete)
 			})
 
+			// Media library
+			r.Route("/media", func(r chi.Router) {
+				r.Use(cache.InvalidateOnWrite(c.InvalidateAll))
+				r.Get("/", uploadHandler.ListMedia)
+				r.With(middleware.RequireAdmin).Get("/usage", uploadHandler.StorageUsage)
+				r.With(middleware.RequireAdmin).Get("/orphans", uploadHandler.Orphans)
+				r.With(middleware.RequireAdmin).Post("/orphans/cleanup", uploadHandler.CleanupOrphans)
+				r.Get("/{id}", uploadHandler.GetMedia)
+				r.Put("/{id}", uploadHandler.UpdateMedia)
+				r.Delete("/{id}", uploadHandler.DeleteMedia)
+				r.Post("/{id}/transform", uploadHandler.TransformMedia)
+			})
+
 			// Telegram
 			r.Route("/telegram", func(r chi.Router) {
 				r.Get("/", telegramHandler.GetAll)
@@ -358,15 +508,19 @@ func (a *App) setupRouter() {
 
 		// Public API (with caching)
 		r.Route("/public", func(r chi.Router) {
-			r.With(cache.Middleware(a.cacheService, cache.KeyPublicWins, cache.DefaultTTL)).Get("/wins", winsHandler.ListPublic)
-			r.With(cache.Middleware(a.cacheService, cache.KeyPublicProjects, cache.DefaultTTL)).Get("/projects", projectsHandler.ListPublic)
+			r.With(cache.MiddlewareWithKey(a.cacheService, wins.PublicCacheKey(cache.KeyPublicWins), cache.DefaultTTL)).Get("/wins", winsHandler.ListPublic)
+			r.With(cache.Middleware(a.cacheService, cache.KeyPublicWinsAnalytics, cache.DefaultTTL)).Get("/wins/analytics", winsHandler.GetPublicAnalytics)
+			r.With(cache.MiddlewareWithKey(a.cacheService, cache.QueryKey(cache.KeyPublicEvents, "when"), cache.DefaultTTL)).Get("/events", eventsHandler.ListPublic)
+			r.With(cache.Middleware(a.cacheService, cache.KeyPublicEventsCalendar, cache.DefaultTTL)).Get("/events.ics", eventsHandler.Calendar)
+			r.With(cache.MiddlewareWithKey(a.cacheService, projects.PublicCacheKey(cache.KeyPublicProjects), cache.DefaultTTL)).Get("/projects", projectsHandler.ListPublic)
+			r.With(cache.MiddlewareWithKey(a.cacheService, cache.ParamKey(cache.KeyPublicProjects, "slug"), cache.DefaultTTL)).Get("/projects/{slug}", projectsHandler.GetPublicBySlug)
 			r.With(cache.Middleware(a.cacheService, cache.KeyPublicTeam, cache.DefaultTTL)).Get("/team", teamHandler.ListPublic)
 			r.With(cache.Middleware(a.cacheService, cache.KeyPublicNews, cache.DefaultTTL)).Get("/news", newsHandler.ListPublic)
 			r.With(cache.Middleware(a.cacheService, cache.KeyPublicPartners, cache.DefaultTTL)).Get("/partners", partnersHandler.ListPublic)
 			r.With(cache.Middleware(a.cacheService, cache.KeyPublicClubs, cache.DefaultTTL)).Get("/clubs", clubsHandler.ListPublic)
-			r.Get("/clubs/{slug}", clubsHandler.GetPublicBySlug)
+			r.With(cache.MiddlewareWithKey(a.cacheService, cache.ParamKey(cache.KeyPublicClubs, "slug"), cache.DefaultTTL)).Get("/clubs/{slug}", clubsHandler.GetPublicBySlug)
 			r.With(cache.Middleware(a.cacheService, cache.KeyPublicBlog, cache.DefaultTTL)).Get("/blog", blogHandler.ListPublic)
-			r.Get("/blog/{slug}", blogHandler.GetPublicBySlug)
+			r.With(cache.MiddlewareWithKey(a.cacheService, cache.ParamKey(cache.KeyPublicBlog, "slug"), cache.DefaultTTL)).Get("/blog/{slug}", blogHandler.GetPublicBySlug)
 			r.With(cache.Middleware(a.cacheService, cache.KeyPublicStats, cache.DefaultTTL)).Get("/stats", statsHandler.ListPublic)
 			r.With(cache.Middleware(a.cacheService, "cache:public:telegram", 15*time.Minute)).Get("/telegram", telegramHandler.GetPublic)
 		})
@@ -375,6 +529,50 @@ func (a *App) setupRouter() {
 	a.router = r
 }
 
+// warmupTargets lists the public endpoints pre-rendered by the cache warmer
+func (a *App) warmupTargets(ctx context.Context) ([]string, error) {
+	paths := []string{
+		"/api/public/wins",
+		"/api/public/wins/analytics",
+		"/api/public/events",
+		"/api/public/events?when=past",
+		"/api/public/events.ics",
+		"/api/public/projects",
+		"/api/public/team",
+		"/api/public/news",
+		"/api/public/partners",
+		"/api/public/clubs",
+		"/api/public/blog",
+		"/api/public/stats",
+	}
+
+	clubList, err := a.clubsService.ListPublic(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range clubList {
+		paths = append(paths, "/api/public/clubs/"+url.PathEscape(c.Slug))
+	}
+
+	projectList, err := a.projectsService.ListPublic(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projectList {
+		paths = append(paths, "/api/public/projects/"+url.PathEscape(p.Slug))
+	}
+
+	posts, err := a.blogService.ListPublic(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range posts {
+		paths = append(paths, "/api/public/blog/"+url.PathEscape(p.Slug))
+	}
+
+	return paths, nil
+}
+
 type HealthResponse struct {
 	Status string `json:"status"`
 	DB     string `json:"db"`
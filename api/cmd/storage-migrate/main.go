@@ -0,0 +1,145 @@
+// Command storage-migrate copies uploaded files from a local upload directory
+// into the storage configured by UPLOAD_STORAGE (typically an S3 bucket).
+//
+// Usage:
+//
+//	storage-migrate [-from /app/uploads] [-dry-run] [-overwrite]
+//
+// Files already present in the destination with the same size are skipped,
+// so the command can be re-run safely. Public URLs do not change: keys keep
+// the same "images/...", "svg/..." and "videos/..." layout under UPLOAD_BASE_URL.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"mime"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+
+	"github.com/itam-misis/itam-api/internal/config"
+	"github.com/itam-misis/itam-api/internal/storage"
+)
+
+// prefixes copied by the migration; incoming/ holds unprocessed uploads and is skipped
+var prefixes = []string{"images/", "svg/", "videos/"}
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	from := flag.String("from", cfg.Upload.Path, "local upload directory to copy from")
+	dryRun := flag.Bool("dry-run", false, "only report what would be copied")
+	overwrite := flag.Bool("overwrite", false, "copy files even if they already exist in the destination")
+	flag.Parse()
+
+	if cfg.Storage.Driver == storage.DriverLocal && path.Clean(*from) == path.Clean(cfg.Upload.Path) {
+		slog.Error("destination is the source directory, set UPLOAD_STORAGE=s3")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	src, err := storage.NewLocal(*from)
+	if err != nil {
+		slog.Error("failed to open source", "error", err)
+		os.Exit(1)
+	}
+
+	dst, err := storage.New(ctx, storage.Config{
+		Driver:    cfg.Storage.Driver,
+		LocalPath: cfg.Upload.Path,
+		S3: storage.S3Config{
+			Endpoint:       cfg.Storage.S3Endpoint,
+			Bucket:         cfg.Storage.S3Bucket,
+			AccessKey:      cfg.Storage.S3AccessKey,
+			SecretKey:      cfg.Storage.S3SecretKey,
+			Region:         cfg.Storage.S3Region,
+			UseSSL:         cfg.Storage.S3UseSSL,
+			PublicEndpoint: cfg.Storage.S3PublicEndpoint,
+			PublicUseSSL:   cfg.Storage.S3PublicUseSSL,
+		},
+	})
+	if err != nil {
+		slog.Error("failed to open destination", "error", err)
+		os.Exit(1)
+	}
+
+	var copied, skipped, failed int
+	var bytes int64
+	for _, prefix := range prefixes {
+		objects, err := src.List(ctx, prefix)
+		if err != nil {
+			slog.Error("failed to list source files", "prefix", prefix, "error", err)
+			os.Exit(1)
+		}
+
+		for _, obj := range objects {
+			if ctx.Err() != nil {
+				slog.Error("interrupted", "copied", copied)
+				os.Exit(1)
+			}
+
+			if !*overwrite {
+				existing, err := dst.Stat(ctx, obj.Key)
+				if err == nil && existing.Size == obj.Size {
+					skipped++
+					continue
+				}
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					slog.Error("failed to check destination", "key", obj.Key, "error", err)
+					failed++
+					continue
+				}
+			}
+
+			if *dryRun {
+				slog.Info("would copy", "key", obj.Key, "size", obj.Size)
+				copied++
+				bytes += obj.Size
+				continue
+			}
+
+			if err := copyObject(ctx, src, dst, obj); err != nil {
+				slog.Error("failed to copy", "key", obj.Key, "error", err)
+				failed++
+				continue
+			}
+			copied++
+			bytes += obj.Size
+		}
+	}
+
+	slog.Info("storage migration finished", "copied", copied, "skipped", skipped, "failed", failed, "bytes", bytes, "dry_run", *dryRun)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func copyObject(ctx context.Context, src, dst storage.Storage, obj storage.Object) error {
+	r, err := src.Get(ctx, obj.Key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(obj.Key))
+	if path.Ext(obj.Key) == ".svg" {
+		contentType = "image/svg+xml"
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return dst.Put(ctx, obj.Key, r, obj.Size, contentType)
+}
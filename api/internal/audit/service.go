@@ -19,15 +19,21 @@ const (
 
 // Entity types
 const (
-	EntityWin     = "win"
-	EntityProject = "project"
-	EntityTeam    = "team"
-	EntityNews    = "news"
-	EntityPartner = "partner"
-	EntityClub    = "club"
-	EntityBlog    = "blog"
-	EntityUser    = "user"
-	EntityStat    = "stat"
+	EntityWin       = "win"
+	EntityProject   = "project"
+	EntityTeam      = "team"
+	EntityNews      = "news"
+	EntityPartner   = "partner"
+	EntityClub      = "club"
+	EntityBlog      = "blog"
+	EntityUser      = "user"
+	EntityStat      = "stat"
+	EntityCache     = "cache"
+	EntityMedia     = "media"
+	EntityHackathon = "hackathon"
+	EntityWinRule   = "win_rule"
+	EntityEvent     = "event"
+	EntityTag       = "tag"
 )
 
 // Log represents an audit log entry
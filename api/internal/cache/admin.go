@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/itam-misis/itam-api/internal/audit"
+)
+
+var (
+	ErrInvalidKey       = errors.New("key must start with " + KeyPrefix)
+	ErrPurgeTargetEmpty = errors.New("one of key, prefix or all is required")
+)
+
+// KeyInfo describes a cached key for the admin listing
+type KeyInfo struct {
+	Key      string           `json:"key"`
+	TTL      int64            `json:"ttl_seconds"` // -1 when the key has no expiry
+	Size     int64            `json:"size"`        // Total bytes across all variants
+	Variants map[string]int64 `json:"variants,omitempty"`
+	Hits     int64            `json:"hits"`
+	StoredAt *time.Time       `json:"stored_at,omitempty"`
+}
+
+// PurgeRequest selects what to purge; exactly one field should be set
+type PurgeRequest struct {
+	Key    string `json:"key,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	All    bool   `json:"all,omitempty"`
+}
+
+// Validate validates the purge request
+func (r *PurgeRequest) Validate() error {
+	switch {
+	case r.All:
+		return nil
+	case r.Key != "":
+		if !strings.HasPrefix(r.Key, KeyPrefix) {
+			return ErrInvalidKey
+		}
+		return nil
+	case r.Prefix != "":
+		if !strings.HasPrefix(r.Prefix, KeyPrefix) {
+			return ErrInvalidKey
+		}
+		return nil
+	}
+	return ErrPurgeTargetEmpty
+}
+
+// PurgeResult is the response for a purge
+type PurgeResult struct {
+	Deleted int `json:"deleted"`
+}
+
+// ListKeys returns every cached key under prefix with its TTL, size and hit count
+func (s *Service) ListKeys(ctx context.Context, prefix string) ([]KeyInfo, error) {
+	if prefix == "" {
+		prefix = KeyPrefix
+	}
+	if !strings.HasPrefix(prefix, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	var keys []string
+	iter := s.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	infos := make([]KeyInfo, 0, len(keys))
+	if len(keys) == 0 {
+		return infos, nil
+	}
+
+	// First pass: type and TTL of every key
+	pipe := s.client.Pipeline()
+	typeCmds := make([]*redis.StatusCmd, len(keys))
+	ttlCmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		typeCmds[i] = pipe.Type(ctx, key)
+		ttlCmds[i] = pipe.TTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	// Second pass: sizes and stats depending on how the key is stored
+	pipe = s.client.Pipeline()
+	hashCmds := make([]*redis.SliceCmd, len(keys))
+	variantCmds := make([]map[string]*redis.Cmd, len(keys))
+	strlenCmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		switch typeCmds[i].Val() {
+		case "hash":
+			hashCmds[i] = pipe.HMGet(ctx, key, fieldHits, fieldStoredAt)
+			variantCmds[i] = map[string]*redis.Cmd{}
+			for _, enc := range []string{EncodingIdentity, EncodingGzip, EncodingBrotli} {
+				variantCmds[i][enc] = pipe.Do(ctx, "HSTRLEN", key, enc)
+			}
+		case "string":
+			strlenCmds[i] = pipe.StrLen(ctx, key)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		info := KeyInfo{Key: key, TTL: -1}
+		if ttl := ttlCmds[i].Val(); ttl > 0 {
+			info.TTL = int64(ttl / time.Second)
+		}
+
+		if strlenCmds[i] != nil {
+			info.Size = strlenCmds[i].Val()
+		}
+
+		if hashCmds[i] != nil {
+			info.Variants = map[string]int64{}
+			for enc, cmd := range variantCmds[i] {
+				if n, _ := cmd.Int64(); n > 0 {
+					info.Variants[enc] = n
+					info.Size += n
+				}
+			}
+
+			vals := hashCmds[i].Val()
+			if len(vals) == 2 {
+				if hits, ok := vals[0].(string); ok {
+					info.Hits, _ = strconv.ParseInt(hits, 10, 64)
+				}
+				if storedAt, ok := vals[1].(string); ok {
+					if ts, err := strconv.ParseInt(storedAt, 10, 64); err == nil {
+						t := time.Unix(ts, 0).UTC()
+						info.StoredAt = &t
+					}
+				}
+			}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Purge removes a single key, a prefix or every cached key and records it in the audit log
+func (s *Service) Purge(ctx context.Context, req *PurgeRequest, userID int64, ip string) (*PurgeResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	var deleted int
+	var err error
+	switch {
+	case req.All:
+		deleted, err = s.DeletePrefix(ctx, KeyPrefix)
+	case req.Key != "":
+		var n int64
+		n, err = s.client.Del(ctx, req.Key).Result()
+		deleted = int(n)
+	default:
+		deleted, err = s.DeletePrefix(ctx, req.Prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionDelete, audit.EntityCache, nil, map[string]any{
+		"action":  "purge",
+		"key":     req.Key,
+		"prefix":  req.Prefix,
+		"all":     req.All,
+		"deleted": deleted,
+	}, ip)
+
+	if deleted > 0 {
+		s.notifyInvalidated()
+	}
+
+	return &PurgeResult{Deleted: deleted}, nil
+}
@@ -4,36 +4,64 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/redis/go-redis/v9"
+
+	"github.com/itam-misis/itam-api/internal/audit"
 )
 
 // Cache keys
 const (
-	KeyPublicWins     = "cache:public:wins"
-	KeyPublicProjects = "cache:public:projects"
-	KeyPublicTeam     = "cache:public:team"
-	KeyPublicNews     = "cache:public:news"
-	KeyPublicPartners = "cache:public:partners"
-	KeyPublicClubs    = "cache:public:clubs"
-	KeyPublicBlog     = "cache:public:blog"
-	KeyPublicStats    = "cache:public:stats"
+	KeyPrefix = "cache:"
+
+	KeyPublicWins           = "cache:public:wins"
+	KeyPublicWinsAnalytics  = "cache:public:wins:analytics"
+	KeyPublicEvents         = "cache:public:events"
+	KeyPublicEventsCalendar = "cache:public:events:ics"
+	KeyPublicProjects       = "cache:public:projects"
+	KeyPublicTeam           = "cache:public:team"
+	KeyPublicNews           = "cache:public:news"
+	KeyPublicPartners       = "cache:public:partners"
+	KeyPublicClubs          = "cache:public:clubs"
+	KeyPublicBlog           = "cache:public:blog"
+	KeyPublicStats          = "cache:public:stats"
 )
 
 // Default TTL
 const DefaultTTL = 5 * time.Minute
 
+// getEntryScript reads an entry and counts the hit only if the entry exists,
+// so a hit on an expiring key never recreates it without a TTL
+var getEntryScript = redis.NewScript(`
+local v = redis.call('HMGET', KEYS[1], 'content_type', 'identity', ARGV[1])
+if v[2] then
+	redis.call('HINCRBY', KEYS[1], 'hits', 1)
+end
+return v
+`)
+
 // Service handles cache operations
 type Service struct {
 	client *redis.Client
+	audit  *audit.Service
+
+	mu        sync.RWMutex
+	listeners []func()
 }
 
 // NewService creates a new cache service
-func NewService(client *redis.Client) *Service {
-	return &Service{client: client}
+func NewService(client *redis.Client, auditService *audit.Service) *Service {
+	return &Service{client: client, audit: auditService}
 }
 
 // Get retrieves a cached value
@@ -46,6 +74,62 @@ func (s *Service) Set(ctx context.Context, key string, value []byte, ttl time.Du
 	return s.client.Set(ctx, key, value, ttl).Err()
 }
 
+// GetEntry retrieves a cached response in the requested encoding and counts the hit.
+// Only the requested variant and the uncompressed fallback are loaded.
+func (s *Service) GetEntry(ctx context.Context, key, encoding string) (*Entry, error) {
+	vals, err := getEntryScript.Run(ctx, s.client, []string{key}, encoding).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) < 3 {
+		return nil, redis.Nil
+	}
+
+	identity, ok := vals[1].(string)
+	if !ok || identity == "" {
+		return nil, redis.Nil
+	}
+
+	e := &Entry{Identity: []byte(identity)}
+	if ct, ok := vals[0].(string); ok {
+		e.ContentType = ct
+	}
+	if variant, ok := vals[2].(string); ok && variant != "" {
+		switch encoding {
+		case EncodingGzip:
+			e.Gzip = []byte(variant)
+		case EncodingBrotli:
+			e.Brotli = []byte(variant)
+		}
+	}
+
+	return e, nil
+}
+
+// SetEntry stores a response with all its precompressed variants
+func (s *Service) SetEntry(ctx context.Context, key string, e *Entry, ttl time.Duration) error {
+	fields := map[string]any{
+		fieldContentType: e.ContentType,
+		fieldIdentity:    e.Identity,
+		fieldHits:        0,
+		fieldStoredAt:    time.Now().Unix(),
+	}
+	if len(e.Gzip) > 0 {
+		fields[fieldGzip] = e.Gzip
+	}
+	if len(e.Brotli) > 0 {
+		fields[fieldBrotli] = e.Brotli
+	}
+
+	// Replace atomically so a stale variant never outlives its body
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, fields)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 // Delete removes a key from cache
 func (s *Service) Delete(ctx context.Context, keys ...string) error {
 	if len(keys) == 0 {
@@ -54,50 +138,116 @@ func (s *Service) Delete(ctx context.Context, keys ...string) error {
 	return s.client.Del(ctx, keys...).Err()
 }
 
+// DeletePrefix removes all keys starting with prefix and returns how many were removed
+func (s *Service) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	var deleted int
+	iter := s.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	batch := make([]string, 0, 100)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			n, err := s.client.Del(ctx, batch...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += int(n)
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	if len(batch) > 0 {
+		n, err := s.client.Del(ctx, batch...).Result()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += int(n)
+	}
+	return deleted, nil
+}
+
+// OnInvalidate registers a callback invoked after public caches are invalidated
+func (s *Service) OnInvalidate(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// notifyInvalidated runs the invalidation callbacks
+func (s *Service) notifyInvalidated() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, fn := range s.listeners {
+		fn()
+	}
+}
+
 // InvalidateWins removes wins cache
 func (s *Service) InvalidateWins(ctx context.Context) {
-	s.Delete(ctx, KeyPublicWins)
+	s.Delete(ctx, KeyPublicWins, KeyPublicWinsAnalytics)
+	s.DeletePrefix(ctx, KeyPublicWins+":")
+	s.notifyInvalidated()
+}
+
+// InvalidateEvents removes events cache
+func (s *Service) InvalidateEvents(ctx context.Context) {
+	s.Delete(ctx, KeyPublicEvents)
+	s.DeletePrefix(ctx, KeyPublicEvents+":")
+	s.notifyInvalidated()
 }
 
 // InvalidateProjects removes projects cache
 func (s *Service) InvalidateProjects(ctx context.Context) {
 	s.Delete(ctx, KeyPublicProjects)
+	s.DeletePrefix(ctx, KeyPublicProjects+":")
+	s.notifyInvalidated()
 }
 
 // InvalidateTeam removes team cache
 func (s *Service) InvalidateTeam(ctx context.Context) {
 	s.Delete(ctx, KeyPublicTeam)
+	s.notifyInvalidated()
 }
 
 // InvalidateNews removes news cache
 func (s *Service) InvalidateNews(ctx context.Context) {
 	s.Delete(ctx, KeyPublicNews)
+	s.notifyInvalidated()
 }
 
 // InvalidatePartners removes partners cache
 func (s *Service) InvalidatePartners(ctx context.Context) {
 	s.Delete(ctx, KeyPublicPartners)
+	s.notifyInvalidated()
 }
 
 // InvalidateClubs removes clubs cache
 func (s *Service) InvalidateClubs(ctx context.Context) {
 	s.Delete(ctx, KeyPublicClubs)
+	s.DeletePrefix(ctx, KeyPublicClubs+":")
+	s.notifyInvalidated()
 }
 
 // InvalidateBlog removes blog cache
 func (s *Service) InvalidateBlog(ctx context.Context) {
 	s.Delete(ctx, KeyPublicBlog)
+	s.DeletePrefix(ctx, KeyPublicBlog+":")
+	s.notifyInvalidated()
 }
 
 // InvalidateStats removes stats cache
 func (s *Service) InvalidateStats(ctx context.Context) {
 	s.Delete(ctx, KeyPublicStats)
+	s.notifyInvalidated()
 }
 
 // InvalidateAll removes all public caches
 func (s *Service) InvalidateAll(ctx context.Context) {
 	s.Delete(ctx,
 		KeyPublicWins,
+		KeyPublicWinsAnalytics,
+		KeyPublicEvents,
 		KeyPublicProjects,
 		KeyPublicTeam,
 		KeyPublicNews,
@@ -106,10 +256,73 @@ func (s *Service) InvalidateAll(ctx context.Context) {
 		KeyPublicBlog,
 		KeyPublicStats,
 	)
+	s.DeletePrefix(ctx, KeyPublicWins+":")
+	s.DeletePrefix(ctx, KeyPublicEvents+":")
+	s.DeletePrefix(ctx, KeyPublicProjects+":")
+	s.DeletePrefix(ctx, KeyPublicClubs+":")
+	s.DeletePrefix(ctx, KeyPublicBlog+":")
+	s.notifyInvalidated()
+}
+
+// InvalidateOnWrite returns a middleware that runs the given invalidations after every successful
+// request that is not a GET or HEAD, so admin edits drop the affected public caches and trigger a warmup
+func InvalidateOnWrite(invalidate ...func(context.Context)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if status := ww.Status(); status != 0 && status >= http.StatusBadRequest {
+				return
+			}
+			ctx := context.WithoutCancel(r.Context())
+			for _, fn := range invalidate {
+				fn(ctx)
+			}
+		})
+	}
 }
 
-// Middleware returns a caching middleware for specific cache key
+// Middleware returns a caching middleware for specific cache key.
+// Responses are compressed once per cache fill and served in the encoding negotiated from Accept-Encoding.
 func Middleware(cacheService *Service, cacheKey string, ttl time.Duration) func(http.Handler) http.Handler {
+	return MiddlewareWithKey(cacheService, func(*http.Request) string { return cacheKey }, ttl)
+}
+
+// ParamKey builds the cache key from a route parameter, e.g. cache:public:clubs:<slug>
+func ParamKey(prefix, param string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return prefix + ":" + chi.URLParam(r, param)
+	}
+}
+
+// QueryKey builds the cache key from the given query parameters in a fixed order,
+// e.g. cache:public:wins:placement=first&sort=placement; without any of them the key is the prefix.
+// Whitespace in values is collapsed, so free text only differing in spacing shares an entry;
+// handlers must bound free-text parameters, as only successful responses are stored.
+func QueryKey(prefix string, params ...string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		query := r.URL.Query()
+		values := url.Values{}
+		for _, param := range params {
+			if v := strings.Join(strings.Fields(query.Get(param)), " "); v != "" {
+				values.Set(param, v)
+			}
+		}
+		if len(values) == 0 {
+			return prefix
+		}
+		return prefix + ":" + values.Encode()
+	}
+}
+
+// MiddlewareWithKey returns a caching middleware that derives the cache key from the request
+func MiddlewareWithKey(cacheService *Service, keyFunc func(*http.Request) string, ttl time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -120,13 +333,16 @@ func Middleware(cacheService *Service, cacheKey string, ttl time.Duration) func(
 				return
 			}
 
-			// Try to get from cache
-			cached, err := cacheService.Get(ctx, cacheKey)
-			if err == nil && len(cached) > 0 {
-				w.Header().Set("Content-Type", "application/json")
-				w.Header().Set("X-Cache", "HIT")
-				w.Write(cached)
-				return
+			cacheKey := keyFunc(r)
+			encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+
+			// Try to get from cache (warmup refreshes skip the lookup)
+			if !isRefresh(ctx) {
+				cached, err := cacheService.GetEntry(ctx, cacheKey, encoding)
+				if err == nil {
+					writeEntry(w, cached, encoding, "HIT")
+					return
+				}
 			}
 
 			// Cache miss - call handler
@@ -137,18 +353,45 @@ func Middleware(cacheService *Service, cacheKey string, ttl time.Duration) func(
 			for k, v := range rec.Header() {
 				w.Header()[k] = v
 			}
-			w.Header().Set("X-Cache", "MISS")
-			w.WriteHeader(rec.Code)
-			w.Write(rec.Body.Bytes())
+
+			if rec.Code != http.StatusOK {
+				w.Header().Set("X-Cache", "MISS")
+				w.WriteHeader(rec.Code)
+				w.Write(rec.Body.Bytes())
+				return
+			}
 
 			// Cache successful responses
-			if rec.Code == http.StatusOK {
-				cacheService.Set(ctx, cacheKey, rec.Body.Bytes(), ttl)
+			entry := NewEntry(rec.Header().Get("Content-Type"), rec.Body.Bytes())
+			if err := cacheService.SetEntry(ctx, cacheKey, entry, ttl); err != nil {
+				slog.Warn("failed to store cache entry", "key", cacheKey, "error", err)
 			}
+
+			writeEntry(w, entry, encoding, "MISS")
 		})
 	}
 }
 
+// writeEntry writes a cached entry in the negotiated encoding
+func writeEntry(w http.ResponseWriter, e *Entry, encoding, cacheStatus string) {
+	body, contentEncoding := e.Variant(encoding)
+
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+	if contentEncoding != "" {
+		w.Header().Set("Content-Encoding", contentEncoding)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("X-Cache", cacheStatus)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
 // responseRecorder wraps http.ResponseWriter to capture response
 type responseRecorder struct {
 	http.ResponseWriter
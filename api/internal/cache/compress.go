@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Content encodings stored for each cached response
+const (
+	EncodingIdentity = "identity"
+	EncodingGzip     = "gzip"
+	EncodingBrotli   = "br"
+)
+
+// Hash fields of a cached entry in Redis
+const (
+	fieldContentType = "content_type"
+	fieldIdentity    = EncodingIdentity
+	fieldGzip        = EncodingGzip
+	fieldBrotli      = EncodingBrotli
+	fieldHits        = "hits"
+	fieldStoredAt    = "stored_at"
+)
+
+// minCompressSize is the body size below which compression is not worth it
+const minCompressSize = 512
+
+// Entry is a cached response body with its precompressed variants
+type Entry struct {
+	ContentType string
+	Identity    []byte
+	Gzip        []byte
+	Brotli      []byte
+}
+
+// NewEntry builds a cache entry and compresses the body once for every supported encoding
+func NewEntry(contentType string, body []byte) *Entry {
+	e := &Entry{
+		ContentType: contentType,
+		Identity:    body,
+	}
+
+	if len(body) < minCompressSize {
+		return e
+	}
+
+	// Keep a variant only when it is actually smaller than the original
+	if gz, err := compressGzip(body); err == nil && len(gz) < len(body) {
+		e.Gzip = gz
+	}
+	if br, err := compressBrotli(body); err == nil && len(br) < len(body) {
+		e.Brotli = br
+	}
+
+	return e
+}
+
+// Variant returns the body for the requested encoding and the Content-Encoding to send.
+// Falls back to the uncompressed body (empty encoding) when the variant is not available.
+func (e *Entry) Variant(encoding string) ([]byte, string) {
+	switch encoding {
+	case EncodingBrotli:
+		if len(e.Brotli) > 0 {
+			return e.Brotli, EncodingBrotli
+		}
+	case EncodingGzip:
+		if len(e.Gzip) > 0 {
+			return e.Gzip, EncodingGzip
+		}
+	}
+	return e.Identity, ""
+}
+
+// Size returns the total number of bytes the entry occupies in cache
+func (e *Entry) Size() int {
+	return len(e.Identity) + len(e.Gzip) + len(e.Brotli)
+}
+
+// NegotiateEncoding picks the best supported encoding from an Accept-Encoding header.
+// Brotli is preferred over gzip when the client accepts both with equal weight.
+func NegotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return EncodingIdentity
+	}
+
+	weights := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if params != "" {
+			params = strings.TrimSpace(params)
+			if v, ok := strings.CutPrefix(params, "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		weights[name] = q
+	}
+
+	weight := func(enc string) float64 {
+		if q, ok := weights[enc]; ok {
+			return q
+		}
+		if q, ok := weights["*"]; ok {
+			return q
+		}
+		return 0
+	}
+
+	best := EncodingIdentity
+	bestQ := 0.0
+	for _, enc := range []string{EncodingBrotli, EncodingGzip} {
+		if q := weight(enc); q > bestQ {
+			best = enc
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+func compressGzip(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func compressBrotli(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	bw := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	if _, err := bw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
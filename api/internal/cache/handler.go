@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// Handler handles cache management HTTP requests
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new cache handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List handles GET /api/cache
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.ListKeys(r.Context(), r.URL.Query().Get("prefix"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidKey) {
+			response.ValidationError(w, err.Error())
+			return
+		}
+		slog.Error("failed to list cache keys", "error", err)
+		response.InternalError(w, "failed to list cache keys")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, keys)
+}
+
+// Purge handles POST /api/cache/purge
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	var req PurgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.Purge(r.Context(), &req, userID, r.RemoteAddr)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrPurgeTargetEmpty):
+			response.ValidationError(w, err.Error())
+		default:
+			slog.Error("failed to purge cache", "error", err)
+			response.InternalError(w, "failed to purge cache")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
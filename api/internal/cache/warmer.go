@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+type contextKey string
+
+const refreshContextKey contextKey = "cache_refresh"
+
+// WithRefresh marks a request so the cache middleware re-renders it instead of serving a cached copy.
+// It is only settable in-process, so public clients cannot bypass the cache.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshContextKey, true)
+}
+
+func isRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshContextKey).(bool)
+	return refresh
+}
+
+// Warmer defaults
+const (
+	DefaultWarmRequestInterval = 100 * time.Millisecond
+	DefaultWarmDebounce        = 2 * time.Second
+)
+
+// TargetsFunc returns the public paths to pre-render
+type TargetsFunc func(ctx context.Context) ([]string, error)
+
+// WarmerConfig configures the cache warmer
+type WarmerConfig struct {
+	RequestInterval time.Duration // Pause between two warmup requests
+	Debounce        time.Duration // Wait after an invalidation so bursts coalesce into one run
+}
+
+// Warmer pre-renders public endpoints through the router so cached output always matches the real handlers
+type Warmer struct {
+	handler http.Handler
+	targets TargetsFunc
+	config  WarmerConfig
+	trigger chan struct{}
+}
+
+// NewWarmer creates a new cache warmer
+func NewWarmer(handler http.Handler, targets TargetsFunc, cfg WarmerConfig) *Warmer {
+	if cfg.RequestInterval <= 0 {
+		cfg.RequestInterval = DefaultWarmRequestInterval
+	}
+	if cfg.Debounce <= 0 {
+		cfg.Debounce = DefaultWarmDebounce
+	}
+	return &Warmer{
+		handler: handler,
+		targets: targets,
+		config:  cfg,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Trigger schedules a warmup run. Triggers that arrive before the run starts are coalesced.
+func (w *Warmer) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes every target on start, then fills missing entries after each trigger until ctx is cancelled
+func (w *Warmer) Run(ctx context.Context) {
+	w.warm(ctx, true)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.trigger:
+		}
+
+		timer := time.NewTimer(w.config.Debounce)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Invalidations during the debounce window are covered by this run
+		select {
+		case <-w.trigger:
+		default:
+		}
+
+		w.warm(ctx, false)
+	}
+}
+
+// warm requests every target, pacing requests by the configured interval
+func (w *Warmer) warm(ctx context.Context, refresh bool) {
+	start := time.Now()
+
+	paths, err := w.targets(ctx)
+	if err != nil {
+		slog.Error("failed to collect cache warmup targets", "error", err)
+		return
+	}
+
+	ticker := time.NewTicker(w.config.RequestInterval)
+	defer ticker.Stop()
+
+	var warmed, failed int
+	for i, path := range paths {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		reqCtx := ctx
+		if refresh {
+			reqCtx = WithRefresh(ctx)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(reqCtx)
+		rec := httptest.NewRecorder()
+		w.handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			slog.Warn("cache warmup request failed", "path", path, "status", rec.Code)
+			failed++
+			continue
+		}
+		warmed++
+	}
+
+	slog.Info("cache warmup finished",
+		"warmed", warmed,
+		"failed", failed,
+		"refresh", refresh,
+		"duration", time.Since(start),
+	)
+}
@@ -34,6 +34,9 @@ type Club struct {
 	Description  *string     `json:"description"`
 	Goal         *string     `json:"goal"`
 	CoverImage   *string     `json:"cover_image"`
+	CoverAlt     *string     `json:"cover_image_alt,omitempty"`     // Public endpoints only, from the media library
+	CoverFocalX  *float64    `json:"cover_image_focal_x,omitempty"` // Focal point for smart cropping, 0..1
+	CoverFocalY  *float64    `json:"cover_image_focal_y,omitempty"`
 	ChatLink     *string     `json:"chat_link"`
 	ChannelLink  *string     `json:"channel_link"`
 	MembersCount int         `json:"members_count"`
@@ -125,25 +128,27 @@ func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, e
 	}
 
 	offset := (params.Page - 1) * params.PageSize
-	baseQuery := "FROM clubs WHERE 1=1"
+	baseQuery := "FROM clubs c LEFT JOIN media md ON md.url = c.cover_image WHERE 1=1"
 	var args []any
 	argNum := 1
 
 	if params.Search != "" {
-		baseQuery += fmt.Sprintf(" AND (name ILIKE $%d OR description ILIKE $%d)", argNum, argNum)
+		baseQuery += fmt.Sprintf(" AND (c.name ILIKE $%d OR c.description ILIKE $%d)", argNum, argNum)
 		args = append(args, "%"+params.Search+"%")
 		argNum++
 	}
 	if params.IsVisible != nil {
-		baseQuery += fmt.Sprintf(" AND is_visible = $%d", argNum)
+		baseQuery += fmt.Sprintf(" AND c.is_visible = $%d", argNum)
 		args = append(args, *params.IsVisible)
 		argNum++
 	}
 
 	var total int
-	s.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total)
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total); err != nil {
+		return nil, err
+	}
 
-	query := fmt.Sprintf(`SELECT id, name, slug, description, goal, cover_image, chat_link, channel_link, members_count, events_count, wins_count, sort_order, is_visible, created_at, updated_at %s ORDER BY sort_order DESC LIMIT $%d OFFSET $%d`, baseQuery, argNum, argNum+1)
+	query := fmt.Sprintf(`SELECT c.id, c.name, c.slug, c.description, c.goal, c.cover_image, md.alt_text, md.focal_x, md.focal_y, c.chat_link, c.channel_link, c.members_count, c.events_count, c.wins_count, c.sort_order, c.is_visible, c.created_at, c.updated_at %s ORDER BY c.sort_order DESC LIMIT $%d OFFSET $%d`, baseQuery, argNum, argNum+1)
 	args = append(args, params.PageSize, offset)
 
 	rows, err := s.db.Query(ctx, query, args...)
@@ -155,10 +160,15 @@ func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, e
 	var clubs []Club
 	for rows.Next() {
 		var c Club
-		rows.Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &c.Goal, &c.CoverImage, &c.ChatLink, &c.ChannelLink, &c.MembersCount, &c.EventsCount, &c.WinsCount, &c.SortOrder, &c.IsVisible, &c.CreatedAt, &c.UpdatedAt)
+		if err := rows.Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &c.Goal, &c.CoverImage, &c.CoverAlt, &c.CoverFocalX, &c.CoverFocalY, &c.ChatLink, &c.ChannelLink, &c.MembersCount, &c.EventsCount, &c.WinsCount, &c.SortOrder, &c.IsVisible, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
 		c.Images, _ = s.getClubImages(ctx, c.ID)
 		clubs = append(clubs, c)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	if clubs == nil {
 		clubs = []Club{}
 	}
@@ -167,7 +177,7 @@ func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, e
 }
 
 func (s *Service) ListPublic(ctx context.Context) ([]Club, error) {
-	rows, err := s.db.Query(ctx, `SELECT id, name, slug, description, goal, cover_image, chat_link, channel_link, members_count, events_count, wins_count, sort_order, is_visible, created_at, updated_at FROM clubs WHERE is_visible = true ORDER BY sort_order DESC`)
+	rows, err := s.db.Query(ctx, `SELECT c.id, c.name, c.slug, c.description, c.goal, c.cover_image, md.alt_text, md.focal_x, md.focal_y, c.chat_link, c.channel_link, c.members_count, c.events_count, c.wins_count, c.sort_order, c.is_visible, c.created_at, c.updated_at FROM clubs c LEFT JOIN media md ON md.url = c.cover_image WHERE c.is_visible = true ORDER BY c.sort_order DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -176,10 +186,15 @@ func (s *Service) ListPublic(ctx context.Context) ([]Club, error) {
 	var clubs []Club
 	for rows.Next() {
 		var c Club
-		rows.Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &c.Goal, &c.CoverImage, &c.ChatLink, &c.ChannelLink, &c.MembersCount, &c.EventsCount, &c.WinsCount, &c.SortOrder, &c.IsVisible, &c.CreatedAt, &c.UpdatedAt)
+		if err := rows.Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &c.Goal, &c.CoverImage, &c.CoverAlt, &c.CoverFocalX, &c.CoverFocalY, &c.ChatLink, &c.ChannelLink, &c.MembersCount, &c.EventsCount, &c.WinsCount, &c.SortOrder, &c.IsVisible, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
 		c.Images, _ = s.getClubImages(ctx, c.ID)
 		clubs = append(clubs, c)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	if clubs == nil {
 		clubs = []Club{}
 	}
@@ -201,7 +216,7 @@ func (s *Service) GetByID(ctx context.Context, id int64) (*Club, error) {
 
 func (s *Service) GetBySlug(ctx context.Context, clubSlug string) (*Club, error) {
 	var c Club
-	err := s.db.QueryRow(ctx, `SELECT id, name, slug, description, goal, cover_image, chat_link, channel_link, members_count, events_count, wins_count, sort_order, is_visible, created_at, updated_at FROM clubs WHERE slug = $1 AND is_visible = true`, clubSlug).Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &c.Goal, &c.CoverImage, &c.ChatLink, &c.ChannelLink, &c.MembersCount, &c.EventsCount, &c.WinsCount, &c.SortOrder, &c.IsVisible, &c.CreatedAt, &c.UpdatedAt)
+	err := s.db.QueryRow(ctx, `SELECT c.id, c.name, c.slug, c.description, c.goal, c.cover_image, md.alt_text, md.focal_x, md.focal_y, c.chat_link, c.channel_link, c.members_count, c.events_count, c.wins_count, c.sort_order, c.is_visible, c.created_at, c.updated_at FROM clubs c LEFT JOIN media md ON md.url = c.cover_image WHERE c.slug = $1 AND c.is_visible = true`, clubSlug).Scan(&c.ID, &c.Name, &c.Slug, &c.Description, &c.Goal, &c.CoverImage, &c.CoverAlt, &c.CoverFocalX, &c.CoverFocalY, &c.ChatLink, &c.ChannelLink, &c.MembersCount, &c.EventsCount, &c.WinsCount, &c.SortOrder, &c.IsVisible, &c.CreatedAt, &c.UpdatedAt)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrClubNotFound
 	}
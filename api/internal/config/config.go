@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,7 @@ type Config struct {
 	Redis    RedisConfig
 	JWT      JWTConfig
 	Upload   UploadConfig
+	Storage  StorageConfig
 }
 
 type ServerConfig struct {
@@ -47,10 +49,38 @@ type JWTConfig struct {
 }
 
 type UploadConfig struct {
-	Path    string
-	MaxSize int64
+	Path          string
+	BaseURL       string
+	MaxSize       int64
+	MaxVideoSize  int64  // Videos are only accepted through chunked upload sessions
+	OrphanCleanup string // off, report or delete
+
+	// Quotas limits the bytes stored by each user of a role; roles without a quota
+	// (or with 0) are unlimited. TotalQuota limits all uploads together, 0 means unlimited.
+	Quotas     map[string]int64
+	TotalQuota int64
+}
+
+// StorageConfig selects where uploads are stored: "local" (UPLOAD_PATH) or "s3"
+type StorageConfig struct {
+	Driver           string
+	S3Endpoint       string
+	S3PublicEndpoint string
+	S3Bucket         string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3Region         string
+	S3UseSSL         bool
+	S3PublicUseSSL   bool
 }
 
+// Orphaned upload cleanup modes
+const (
+	OrphanCleanupOff    = "off"
+	OrphanCleanupReport = "report"
+	OrphanCleanupDelete = "delete"
+)
+
 func Load() (*Config, error) {
 	jwtExpiry, err := time.ParseDuration(getEnv("JWT_EXPIRY", "720h"))
 	if err != nil {
@@ -62,6 +92,16 @@ func Load() (*Config, error) {
 		maxSize = 5 * 1024 * 1024 // 5MB default
 	}
 
+	maxVideoSize, err := strconv.ParseInt(getEnv("UPLOAD_MAX_VIDEO_SIZE", "209715200"), 10, 64)
+	if err != nil {
+		maxVideoSize = 200 * 1024 * 1024 // 200MB default
+	}
+
+	quotas := map[string]int64{
+		"admin":  getEnvInt64("UPLOAD_QUOTA_ADMIN", 0),
+		"editor": getEnvInt64("UPLOAD_QUOTA_EDITOR", 1024*1024*1024), // 1GB per editor
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
 			Port:         getEnv("API_PORT", "8080"),
@@ -84,8 +124,24 @@ func Load() (*Config, error) {
 			Expiry: jwtExpiry,
 		},
 		Upload: UploadConfig{
-			Path:    getEnv("UPLOAD_PATH", "/opt/itam/uploads"),
-			MaxSize: maxSize,
+			Path:          getEnv("UPLOAD_PATH", "/opt/itam/uploads"),
+			BaseURL:       strings.TrimSuffix(getEnv("UPLOAD_BASE_URL", "/uploads"), "/"),
+			MaxSize:       maxSize,
+			MaxVideoSize:  maxVideoSize,
+			OrphanCleanup: getEnv("UPLOAD_ORPHAN_CLEANUP", OrphanCleanupReport),
+			Quotas:        quotas,
+			TotalQuota:    getEnvInt64("UPLOAD_QUOTA_TOTAL", 0),
+		},
+		Storage: StorageConfig{
+			Driver:           getEnv("UPLOAD_STORAGE", "local"),
+			S3Endpoint:       getEnv("S3_ENDPOINT", ""),
+			S3PublicEndpoint: getEnv("S3_PUBLIC_ENDPOINT", ""),
+			S3Bucket:         getEnv("S3_BUCKET", "itam-uploads"),
+			S3AccessKey:      getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey:      getEnv("S3_SECRET_KEY", ""),
+			S3Region:         getEnv("S3_REGION", ""),
+			S3UseSSL:         getEnv("S3_USE_SSL", "false") == "true",
+			S3PublicUseSSL:   getEnv("S3_PUBLIC_USE_SSL", "true") == "true",
 		},
 	}
 
@@ -103,6 +159,20 @@ func (c *Config) validate() error {
 	if c.JWT.Secret == "" {
 		return fmt.Errorf("JWT_SECRET is required")
 	}
+	switch c.Upload.OrphanCleanup {
+	case OrphanCleanupOff, OrphanCleanupReport, OrphanCleanupDelete:
+	default:
+		return fmt.Errorf("UPLOAD_ORPHAN_CLEANUP must be one of off, report, delete")
+	}
+	switch c.Storage.Driver {
+	case "local":
+	case "s3":
+		if c.Storage.S3Endpoint == "" || c.Storage.S3AccessKey == "" || c.Storage.S3SecretKey == "" {
+			return fmt.Errorf("S3_ENDPOINT, S3_ACCESS_KEY and S3_SECRET_KEY are required for UPLOAD_STORAGE=s3")
+		}
+	default:
+		return fmt.Errorf("UPLOAD_STORAGE must be local or s3")
+	}
 	return nil
 }
 
@@ -112,3 +182,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt64 reads an integer variable, falling back to the default when it is unset or malformed
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(getEnv(key, ""), 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
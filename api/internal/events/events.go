@@ -0,0 +1,783 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/itam-misis/itam-api/internal/audit"
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+	"github.com/itam-misis/itam-api/internal/tags"
+	"github.com/itam-misis/itam-api/internal/wins"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrEventNotFound    = errors.New("event not found")
+	ErrTitleRequired    = errors.New("title is required")
+	ErrStartRequired    = errors.New("start is required")
+	ErrInvalidTime      = errors.New("invalid time, use YYYY-MM-DD, YYYY-MM-DDTHH:MM or RFC 3339")
+	ErrInvalidTimes     = errors.New("end is before start")
+	ErrInvalidFormat    = errors.New("format must be online, offline or hybrid")
+	ErrClubNotFound     = errors.New("club not found")
+	ErrEventNotFinished = errors.New("event has not finished yet")
+)
+
+// Event formats
+const (
+	FormatOnline  = "online"
+	FormatOffline = "offline"
+	FormatHybrid  = "hybrid"
+)
+
+// Listings by time
+const (
+	WhenUpcoming = "upcoming" // Not finished yet, soonest first
+	WhenPast     = "past"     // Finished, latest first
+)
+
+// Location is the time zone of times given without an offset
+var Location = time.FixedZone("MSK", 3*60*60)
+
+type Event struct {
+	ID                   int64      `json:"id"`
+	Title                string     `json:"title"`
+	Organizer            *string    `json:"organizer"`
+	Description          *string    `json:"description"`
+	StartAt              time.Time  `json:"start_at"`
+	EndAt                *time.Time `json:"end_at"`
+	RegistrationDeadline *time.Time `json:"registration_deadline"`
+	Link                 *string    `json:"link"`
+	Format               string     `json:"format"`
+	Location             *string    `json:"location"`
+	ClubID               *int64     `json:"club_id"`
+	ClubName             *string    `json:"club_name"`
+	HackathonID          *int64     `json:"hackathon_id"` // Hackathon of the wins recorded for the event
+	IsVisible            bool       `json:"is_visible"`
+	Tags                 []Tag      `json:"tags"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// finishesAt is the end of the event, or its start when the end is unknown
+func (e *Event) finishesAt() time.Time {
+	if e.EndAt != nil {
+		return *e.EndAt
+	}
+	return e.StartAt
+}
+
+// hackathonDetails describe the hackathon created for wins at the event
+func (e *Event) hackathonDetails() *wins.HackathonDetails {
+	start := e.StartAt.In(Location)
+	end := e.finishesAt().In(Location)
+	startDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+
+	details := &wins.HackathonDetails{
+		Organizer: e.Organizer,
+		IsOnline:  e.Format != FormatOffline,
+		StartDate: &startDate,
+		EndDate:   &endDate,
+		URL:       e.Link,
+	}
+	if e.Format != FormatOnline {
+		details.City = e.Location
+	}
+	return details
+}
+
+// Times are YYYY-MM-DD, YYYY-MM-DDTHH:MM (both in Location) or RFC 3339
+type CreateRequest struct {
+	Title                string   `json:"title"`
+	Organizer            *string  `json:"organizer"`
+	Description          *string  `json:"description"`
+	StartAt              string   `json:"start_at"`
+	EndAt                *string  `json:"end_at"`
+	RegistrationDeadline *string  `json:"registration_deadline"`
+	Link                 *string  `json:"link"`
+	Format               string   `json:"format"` // offline by default
+	Location             *string  `json:"location"`
+	ClubID               *int64   `json:"club_id"`
+	IsVisible            bool     `json:"is_visible"`
+	TagIDs               []int64  `json:"tag_ids"`
+	TagNames             []string `json:"tag_names"` // Create new tags by name
+}
+
+type UpdateRequest struct {
+	Title                *string  `json:"title,omitempty"`
+	Organizer            *string  `json:"organizer,omitempty"`
+	Description          *string  `json:"description,omitempty"`
+	StartAt              *string  `json:"start_at,omitempty"`
+	EndAt                *string  `json:"end_at,omitempty"` // Empty string clears the time
+	RegistrationDeadline *string  `json:"registration_deadline,omitempty"`
+	Link                 *string  `json:"link,omitempty"`
+	Format               *string  `json:"format,omitempty"`
+	Location             *string  `json:"location,omitempty"`
+	ClubID               *int64   `json:"club_id,omitempty"` // 0 removes the club
+	IsVisible            *bool    `json:"is_visible,omitempty"`
+	TagIDs               []int64  `json:"tag_ids,omitempty"`
+	TagNames             []string `json:"tag_names,omitempty"`
+}
+
+// ConvertRequest records a win at a finished event. The hackathon of the event is reused
+// for every win; the first conversion finds or creates it by the event title.
+type ConvertRequest struct {
+	TeamName       string  `json:"team_name"`
+	Result         string  `json:"result"`
+	Prize          int     `json:"prize"`
+	Currency       string  `json:"currency"`
+	Link           *string `json:"link"` // The event link by default
+	ParticipantIDs []int64 `json:"participant_ids"`
+}
+
+type ListParams struct {
+	Page     int
+	PageSize int
+	Search   string
+	When     string // upcoming, past or all when empty
+	ClubID   int64
+}
+
+type ListResponse struct {
+	Events     []Event `json:"events"`
+	Total      int     `json:"total"`
+	Page       int     `json:"page"`
+	PageSize   int     `json:"page_size"`
+	TotalPages int     `json:"total_pages"`
+}
+
+// parseTime parses an optional time; nil or "" means no time.
+// A date alone is the start of the day, or its end with endOfDay (for ends and deadlines).
+func parseTime(s *string, endOfDay bool) (*time.Time, error) {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil, nil
+	}
+	value := strings.TrimSpace(*s)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", value, Location); err == nil {
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, Location); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return &t, nil
+	}
+	return nil, ErrInvalidTime
+}
+
+// nullable maps empty strings to NULL
+func nullable(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	return &trimmed
+}
+
+func validFormat(format string) bool {
+	return format == FormatOnline || format == FormatOffline || format == FormatHybrid
+}
+
+// Service
+type Service struct {
+	db    *pgxpool.Pool
+	audit *audit.Service
+	wins  *wins.Service
+}
+
+func NewService(db *pgxpool.Pool, auditService *audit.Service, winsService *wins.Service) *Service {
+	return &Service{db: db, audit: auditService, wins: winsService}
+}
+
+const eventColumns = `e.id, e.title, e.organizer, e.description, e.start_at, e.end_at, e.registration_deadline,
+	e.link, e.format, e.location, e.club_id, c.name, e.hackathon_id, e.is_visible,
+	COALESCE((
+		SELECT json_agg(json_build_object('id', t.id, 'name', t.name, 'slug', t.slug) ORDER BY t.name)
+		FROM event_tags et JOIN tags t ON t.id = et.tag_id
+		WHERE et.event_id = e.id
+	), '[]'), e.created_at, e.updated_at`
+
+const eventTables = "events e LEFT JOIN clubs c ON c.id = e.club_id"
+
+// whenCondition selects events by whether they have finished
+var whenCondition = map[string]string{
+	WhenUpcoming: " AND COALESCE(e.end_at, e.start_at) >= NOW()",
+	WhenPast:     " AND COALESCE(e.end_at, e.start_at) < NOW()",
+}
+
+// whenOrder lists upcoming events soonest first and everything else latest first
+func whenOrder(when string) string {
+	if when == WhenUpcoming {
+		return "e.start_at, e.id"
+	}
+	return "e.start_at DESC, e.id DESC"
+}
+
+func scanEvent(row pgx.Row, e *Event) error {
+	return row.Scan(
+		&e.ID, &e.Title, &e.Organizer, &e.Description, &e.StartAt, &e.EndAt, &e.RegistrationDeadline,
+		&e.Link, &e.Format, &e.Location, &e.ClubID, &e.ClubName, &e.HackathonID, &e.IsVisible,
+		&e.Tags, &e.CreatedAt, &e.UpdatedAt,
+	)
+}
+
+func (s *Service) query(ctx context.Context, query string, args ...any) ([]Event, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := scanEvent(rows, &e); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 20
+	}
+
+	offset := (params.Page - 1) * params.PageSize
+	baseQuery := "FROM " + eventTables + " WHERE 1=1" + whenCondition[params.When]
+	var args []any
+	argNum := 1
+
+	if params.Search != "" {
+		baseQuery += fmt.Sprintf(" AND (e.title ILIKE $%d OR e.organizer ILIKE $%d OR e.location ILIKE $%d)", argNum, argNum, argNum)
+		args = append(args, "%"+params.Search+"%")
+		argNum++
+	}
+	if params.ClubID != 0 {
+		baseQuery += fmt.Sprintf(" AND e.club_id = $%d", argNum)
+		args = append(args, params.ClubID)
+		argNum++
+	}
+
+	var total int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s %s ORDER BY %s LIMIT $%d OFFSET $%d`, eventColumns, baseQuery, whenOrder(params.When), argNum, argNum+1)
+	args = append(args, params.PageSize, offset)
+
+	events, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListResponse{Events: events, Total: total, Page: params.Page, PageSize: params.PageSize, TotalPages: (total + params.PageSize - 1) / params.PageSize}, nil
+}
+
+// ListPublic returns the visible upcoming or past events
+func (s *Service) ListPublic(ctx context.Context, when string) ([]Event, error) {
+	if when != WhenPast {
+		when = WhenUpcoming
+	}
+	return s.query(ctx, `SELECT `+eventColumns+` FROM `+eventTables+` WHERE e.is_visible = true`+whenCondition[when]+` ORDER BY `+whenOrder(when))
+}
+
+func (s *Service) GetByID(ctx context.Context, id int64) (*Event, error) {
+	var e Event
+	err := scanEvent(s.db.QueryRow(ctx, "SELECT "+eventColumns+" FROM "+eventTables+" WHERE e.id = $1", id), &e)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Service) Create(ctx context.Context, req *CreateRequest, userID int64, ip string) (*Event, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, ErrTitleRequired
+	}
+	if strings.TrimSpace(req.StartAt) == "" {
+		return nil, ErrStartRequired
+	}
+	startAt, err := parseTime(&req.StartAt, false)
+	if err != nil {
+		return nil, err
+	}
+	endAt, err := parseTime(req.EndAt, true)
+	if err != nil {
+		return nil, err
+	}
+	if endAt != nil && endAt.Before(*startAt) {
+		return nil, ErrInvalidTimes
+	}
+	deadline, err := parseTime(req.RegistrationDeadline, true)
+	if err != nil {
+		return nil, err
+	}
+	format := req.Format
+	if format == "" {
+		format = FormatOffline
+	}
+	if !validFormat(format) {
+		return nil, ErrInvalidFormat
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO events (title, organizer, description, start_at, end_at, registration_deadline, link, format, location, club_id, is_visible)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`, title, nullable(req.Organizer), nullable(req.Description), startAt, endAt, deadline,
+		nullable(req.Link), format, nullable(req.Location), req.ClubID, req.IsVisible,
+	).Scan(&id)
+	if err != nil {
+		return nil, clubError(err, "failed to create event")
+	}
+
+	if err := syncTags(ctx, tx, id, req.TagIDs, req.TagNames); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	e, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityEvent, &e.ID, e, ip)
+	return e, nil
+}
+
+func (s *Service) Update(ctx context.Context, id int64, req *UpdateRequest, userID int64, ip string) (*Event, error) {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	setParts := []string{}
+	args := []any{}
+	argNum := 1
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, ErrTitleRequired
+		}
+		setParts = append(setParts, fmt.Sprintf("title = $%d", argNum))
+		args = append(args, title)
+		argNum++
+	}
+	if req.Organizer != nil {
+		setParts = append(setParts, fmt.Sprintf("organizer = $%d", argNum))
+		args = append(args, nullable(req.Organizer))
+		argNum++
+	}
+	if req.Description != nil {
+		setParts = append(setParts, fmt.Sprintf("description = $%d", argNum))
+		args = append(args, nullable(req.Description))
+		argNum++
+	}
+	startAt, endAt := &existing.StartAt, existing.EndAt
+	if req.StartAt != nil {
+		if startAt, err = parseTime(req.StartAt, false); err != nil {
+			return nil, err
+		}
+		if startAt == nil {
+			return nil, ErrStartRequired
+		}
+		setParts = append(setParts, fmt.Sprintf("start_at = $%d", argNum))
+		args = append(args, startAt)
+		argNum++
+	}
+	if req.EndAt != nil {
+		if endAt, err = parseTime(req.EndAt, true); err != nil {
+			return nil, err
+		}
+		setParts = append(setParts, fmt.Sprintf("end_at = $%d", argNum))
+		args = append(args, endAt)
+		argNum++
+	}
+	if endAt != nil && endAt.Before(*startAt) {
+		return nil, ErrInvalidTimes
+	}
+	if req.RegistrationDeadline != nil {
+		deadline, err := parseTime(req.RegistrationDeadline, true)
+		if err != nil {
+			return nil, err
+		}
+		setParts = append(setParts, fmt.Sprintf("registration_deadline = $%d", argNum))
+		args = append(args, deadline)
+		argNum++
+	}
+	if req.Link != nil {
+		setParts = append(setParts, fmt.Sprintf("link = $%d", argNum))
+		args = append(args, nullable(req.Link))
+		argNum++
+	}
+	if req.Format != nil {
+		if !validFormat(*req.Format) {
+			return nil, ErrInvalidFormat
+		}
+		setParts = append(setParts, fmt.Sprintf("format = $%d", argNum))
+		args = append(args, *req.Format)
+		argNum++
+	}
+	if req.Location != nil {
+		setParts = append(setParts, fmt.Sprintf("location = $%d", argNum))
+		args = append(args, nullable(req.Location))
+		argNum++
+	}
+	if req.ClubID != nil {
+		var clubID *int64
+		if *req.ClubID != 0 {
+			clubID = req.ClubID
+		}
+		setParts = append(setParts, fmt.Sprintf("club_id = $%d", argNum))
+		args = append(args, clubID)
+		argNum++
+	}
+	if req.IsVisible != nil {
+		setParts = append(setParts, fmt.Sprintf("is_visible = $%d", argNum))
+		args = append(args, *req.IsVisible)
+		argNum++
+	}
+
+	syncingTags := req.TagIDs != nil || req.TagNames != nil
+	if len(setParts) == 0 && !syncingTags {
+		return existing, nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if len(setParts) > 0 {
+		args = append(args, id)
+		_, err = tx.Exec(ctx, fmt.Sprintf(`UPDATE events SET %s WHERE id = $%d`, strings.Join(setParts, ", "), argNum), args...)
+		if err != nil {
+			return nil, clubError(err, "failed to update event")
+		}
+	}
+	if syncingTags {
+		if err := syncTags(ctx, tx, id, req.TagIDs, req.TagNames); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	e, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityEvent, &e.ID, map[string]any{"before": existing, "after": e}, ip)
+	return e, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id int64, userID int64, ip string) error {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(ctx, "DELETE FROM events WHERE id = $1", id); err != nil {
+		return err
+	}
+	s.audit.LogAction(ctx, &userID, audit.ActionDelete, audit.EntityEvent, &id, existing, ip)
+	return nil
+}
+
+// ConvertToWin records a win at a finished event. The award date is the last day of the event.
+// Without a linked hackathon, one named after the event is matched or created from its details.
+func (s *Service) ConvertToWin(ctx context.Context, id int64, req *ConvertRequest, userID int64, ip string) (*wins.Win, error) {
+	e, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	finished := e.finishesAt()
+	if finished.After(time.Now()) {
+		return nil, ErrEventNotFinished
+	}
+
+	awardDate := finished.In(Location).Format("2006-01-02")
+	link := req.Link
+	if link == nil || strings.TrimSpace(*link) == "" {
+		link = e.Link
+	}
+
+	win, err := s.wins.Create(ctx, &wins.CreateWinRequest{
+		TeamName:       strings.TrimSpace(req.TeamName),
+		HackathonID:    e.HackathonID,
+		HackathonName:  e.Title,
+		Result:         strings.TrimSpace(req.Result),
+		Prize:          req.Prize,
+		Currency:       req.Currency,
+		AwardDate:      &awardDate,
+		Year:           finished.In(Location).Year(),
+		Link:           link,
+		ParticipantIDs: req.ParticipantIDs,
+		Hackathon:      e.hackathonDetails(),
+	}, userID, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	// Later wins at the event reuse its hackathon
+	if e.HackathonID == nil {
+		if _, err := s.db.Exec(ctx, "UPDATE events SET hackathon_id = $1 WHERE id = $2", win.HackathonID, id); err != nil {
+			return nil, fmt.Errorf("failed to link event to hackathon: %w", err)
+		}
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityEvent, &id, map[string]any{
+		"action":       "convert_to_win",
+		"win_id":       win.ID,
+		"hackathon_id": win.HackathonID,
+	}, ip)
+	return win, nil
+}
+
+// clubError maps a missing club to ErrClubNotFound
+func clubError(err error, message string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrClubNotFound
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// syncTags replaces the tags of an event, creating tags given by name
+func syncTags(ctx context.Context, tx pgx.Tx, eventID int64, tagIDs []int64, tagNames []string) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM event_tags WHERE event_id = $1", eventID); err != nil {
+		return err
+	}
+
+	for _, name := range tagNames {
+		if tags.Normalize(name) == "" {
+			continue
+		}
+		tagID, err := tags.Resolve(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		tagIDs = append(tagIDs, tagID)
+	}
+
+	for _, tagID := range tagIDs {
+		_, err := tx.Exec(ctx, "INSERT INTO event_tags (event_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", eventID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// writeError maps service errors to responses
+func writeError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, ErrEventNotFound):
+		response.NotFound(w, "event not found")
+	case errors.Is(err, ErrTitleRequired), errors.Is(err, ErrStartRequired), errors.Is(err, ErrInvalidTime),
+		errors.Is(err, ErrInvalidTimes), errors.Is(err, ErrInvalidFormat), errors.Is(err, ErrClubNotFound):
+		response.ValidationError(w, err.Error())
+	case errors.Is(err, ErrEventNotFinished):
+		response.Conflict(w, err.Error())
+	default:
+		slog.Error("failed to "+action+" event", "error", err)
+		response.InternalError(w, "failed to "+action+" event")
+	}
+}
+
+// List handles GET /api/events?search=&when=upcoming|past&club_id=
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	params := ListParams{Page: 1, PageSize: 20, Search: r.URL.Query().Get("search"), When: r.URL.Query().Get("when")}
+	if p, _ := strconv.Atoi(r.URL.Query().Get("page")); p > 0 {
+		params.Page = p
+	}
+	if ps, _ := strconv.Atoi(r.URL.Query().Get("page_size")); ps > 0 {
+		params.PageSize = ps
+	}
+	if clubID, err := strconv.ParseInt(r.URL.Query().Get("club_id"), 10, 64); err == nil {
+		params.ClubID = clubID
+	}
+
+	result, err := h.service.List(r.Context(), params)
+	if err != nil {
+		writeError(w, err, "list")
+		return
+	}
+	response.JSON(w, http.StatusOK, result)
+}
+
+// ListPublic handles GET /api/public/events?when=upcoming|past
+func (h *Handler) ListPublic(w http.ResponseWriter, r *http.Request) {
+	when := strings.TrimSpace(r.URL.Query().Get("when"))
+	if when != "" && when != WhenUpcoming && when != WhenPast {
+		response.ValidationError(w, "when must be upcoming or past")
+		return
+	}
+
+	events, err := h.service.ListPublic(r.Context(), when)
+	if err != nil {
+		slog.Error("failed to list public events", "error", err)
+		response.InternalError(w, "failed to list events")
+		return
+	}
+	response.JSON(w, http.StatusOK, events)
+}
+
+// Get handles GET /api/events/:id
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid event id")
+		return
+	}
+
+	event, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "get")
+		return
+	}
+	response.JSON(w, http.StatusOK, event)
+}
+
+// Create handles POST /api/events
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	event, err := h.service.Create(r.Context(), &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeError(w, err, "create")
+		return
+	}
+	response.JSON(w, http.StatusCreated, event)
+}
+
+// Update handles PUT /api/events/:id
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid event id")
+		return
+	}
+
+	var req UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	event, err := h.service.Update(r.Context(), id, &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeError(w, err, "update")
+		return
+	}
+	response.JSON(w, http.StatusOK, event)
+}
+
+// Delete handles DELETE /api/events/:id
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid event id")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.service.Delete(r.Context(), id, userID, r.RemoteAddr); err != nil {
+		writeError(w, err, "delete")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "event deleted"})
+}
+
+// ConvertToWin handles POST /api/events/:id/win
+func (h *Handler) ConvertToWin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid event id")
+		return
+	}
+
+	var req ConvertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	win, err := h.service.ConvertToWin(r.Context(), id, &req, userID, r.RemoteAddr)
+	if err != nil {
+		switch {
+		case errors.Is(err, wins.ErrTeamNameRequired), errors.Is(err, wins.ErrResultRequired),
+			errors.Is(err, wins.ErrInvalidYear), errors.Is(err, wins.ErrInvalidCurrency),
+			errors.Is(err, wins.ErrMemberNotFound):
+			response.ValidationError(w, err.Error())
+		default:
+			writeError(w, err, "convert")
+		}
+		return
+	}
+	response.JSON(w, http.StatusCreated, win)
+}
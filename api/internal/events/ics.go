@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// icsHistory is how long finished events stay in the feed
+const icsHistory = "1 year"
+
+// icsUIDDomain makes event UIDs globally unique
+const icsUIDDomain = "itam.misis.ru"
+
+// ListCalendar returns the visible events for the iCalendar feed: upcoming ones and those of the last year
+func (s *Service) ListCalendar(ctx context.Context) ([]Event, error) {
+	return s.query(ctx, `SELECT `+eventColumns+` FROM `+eventTables+`
+		WHERE e.is_visible = true AND COALESCE(e.end_at, e.start_at) >= NOW() - $1::interval
+		ORDER BY e.start_at, e.id`, icsHistory)
+}
+
+// writeCalendar renders events as an RFC 5545 calendar
+func writeCalendar(events []Event) string {
+	var b strings.Builder
+	line := func(name, value string) {
+		b.WriteString(foldLine(name + ":" + value))
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//ITAM//Events//RU")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", "ITAM: хакатоны и события")
+	line("X-WR-TIMEZONE", "Europe/Moscow")
+
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("event-%d@%s", e.ID, icsUIDDomain))
+		line("DTSTAMP", icsTime(e.UpdatedAt))
+		line("LAST-MODIFIED", icsTime(e.UpdatedAt))
+		line("DTSTART", icsTime(e.StartAt))
+		if e.EndAt != nil {
+			line("DTEND", icsTime(*e.EndAt))
+		}
+		line("SUMMARY", icsText(e.Title))
+
+		var description []string
+		if e.Organizer != nil {
+			description = append(description, "Организатор: "+*e.Organizer)
+		}
+		if e.RegistrationDeadline != nil {
+			description = append(description, "Регистрация до "+e.RegistrationDeadline.In(Location).Format("02.01.2006 15:04")+" МСК")
+		}
+		if e.Description != nil {
+			description = append(description, *e.Description)
+		}
+		if e.Link != nil {
+			description = append(description, *e.Link)
+		}
+		if len(description) > 0 {
+			line("DESCRIPTION", icsText(strings.Join(description, "\n\n")))
+		}
+
+		switch {
+		case e.Location != nil:
+			line("LOCATION", icsText(*e.Location))
+		case e.Format == FormatOnline:
+			line("LOCATION", "Онлайн")
+		}
+		if e.Link != nil {
+			line("URL", *e.Link)
+		}
+		if len(e.Tags) > 0 {
+			names := make([]string, len(e.Tags))
+			for i, t := range e.Tags {
+				names[i] = icsText(t.Name)
+			}
+			line("CATEGORIES", strings.Join(names, ","))
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return b.String()
+}
+
+// icsTime formats a time in UTC
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsText escapes a TEXT value
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldLine ends a content line with CRLF, folding it into lines of at most 75 octets
+// without splitting UTF-8 characters
+func foldLine(s string) string {
+	const limit = 75
+
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// Calendar handles GET /api/public/events.ics
+func (h *Handler) Calendar(w http.ResponseWriter, r *http.Request) {
+	events, err := h.service.ListCalendar(r.Context())
+	if err != nil {
+		slog.Error("failed to list calendar events", "error", err)
+		response.InternalError(w, "failed to build calendar")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(writeCalendar(events)))
+}
@@ -0,0 +1,446 @@
+package hackathons
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/itam-misis/itam-api/internal/audit"
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrHackathonNotFound = errors.New("hackathon not found")
+	ErrNameRequired      = errors.New("name is required")
+	ErrNameExists        = errors.New("hackathon with this name already exists")
+	ErrInvalidDate       = errors.New("invalid date, use YYYY-MM-DD")
+	ErrInvalidDates      = errors.New("end date is before start date")
+	ErrHackathonInUse    = errors.New("hackathon has wins")
+)
+
+const dateLayout = "2006-01-02"
+
+type Hackathon struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Organizer *string    `json:"organizer"`
+	City      *string    `json:"city"`
+	IsOnline  bool       `json:"is_online"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	URL       *string    `json:"url"`
+	WinsCount int        `json:"wins_count"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type CreateRequest struct {
+	Name      string  `json:"name"`
+	Organizer *string `json:"organizer"`
+	City      *string `json:"city"`
+	IsOnline  bool    `json:"is_online"`
+	StartDate *string `json:"start_date"` // YYYY-MM-DD
+	EndDate   *string `json:"end_date"`
+	URL       *string `json:"url"`
+}
+
+type UpdateRequest struct {
+	Name      *string `json:"name,omitempty"`
+	Organizer *string `json:"organizer,omitempty"`
+	City      *string `json:"city,omitempty"`
+	IsOnline  *bool   `json:"is_online,omitempty"`
+	StartDate *string `json:"start_date,omitempty"` // Empty string clears the date
+	EndDate   *string `json:"end_date,omitempty"`
+	URL       *string `json:"url,omitempty"`
+}
+
+type ListParams struct {
+	Page     int
+	PageSize int
+	Search   string
+}
+
+type ListResponse struct {
+	Hackathons []Hackathon `json:"hackathons"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	TotalPages int         `json:"total_pages"`
+}
+
+// normalizeName collapses whitespace; names are unique case-insensitively
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// parseDate parses an optional date; nil or "" means no date
+func parseDate(s *string) (*time.Time, error) {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(dateLayout, strings.TrimSpace(*s))
+	if err != nil {
+		return nil, ErrInvalidDate
+	}
+	return &t, nil
+}
+
+// nullable maps empty strings to NULL
+func nullable(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	return &trimmed
+}
+
+// Service
+type Service struct {
+	db    *pgxpool.Pool
+	audit *audit.Service
+}
+
+func NewService(db *pgxpool.Pool, auditService *audit.Service) *Service {
+	return &Service{db: db, audit: auditService}
+}
+
+const hackathonColumns = `h.id, h.name, h.organizer, h.city, h.is_online, h.start_date, h.end_date, h.url,
+	(SELECT COUNT(*) FROM wins w WHERE w.hackathon_id = h.id), h.created_at, h.updated_at`
+
+func scanHackathon(row pgx.Row, h *Hackathon) error {
+	return row.Scan(&h.ID, &h.Name, &h.Organizer, &h.City, &h.IsOnline, &h.StartDate, &h.EndDate, &h.URL, &h.WinsCount, &h.CreatedAt, &h.UpdatedAt)
+}
+
+func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 20
+	}
+
+	offset := (params.Page - 1) * params.PageSize
+	baseQuery := "FROM hackathons h WHERE 1=1"
+	var args []any
+	argNum := 1
+
+	if params.Search != "" {
+		baseQuery += fmt.Sprintf(" AND (h.name ILIKE $%d OR h.organizer ILIKE $%d OR h.city ILIKE $%d)", argNum, argNum, argNum)
+		args = append(args, "%"+params.Search+"%")
+		argNum++
+	}
+
+	var total int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count hackathons: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s %s ORDER BY h.start_date DESC NULLS LAST, h.name LIMIT $%d OFFSET $%d`, hackathonColumns, baseQuery, argNum, argNum+1)
+	args = append(args, params.PageSize, offset)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hackathons: %w", err)
+	}
+	defer rows.Close()
+
+	var hackathons []Hackathon
+	for rows.Next() {
+		var h Hackathon
+		if err := scanHackathon(rows, &h); err != nil {
+			return nil, fmt.Errorf("failed to scan hackathon: %w", err)
+		}
+		hackathons = append(hackathons, h)
+	}
+	if hackathons == nil {
+		hackathons = []Hackathon{}
+	}
+
+	return &ListResponse{Hackathons: hackathons, Total: total, Page: params.Page, PageSize: params.PageSize, TotalPages: (total + params.PageSize - 1) / params.PageSize}, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id int64) (*Hackathon, error) {
+	var h Hackathon
+	err := scanHackathon(s.db.QueryRow(ctx, "SELECT "+hackathonColumns+" FROM hackathons h WHERE h.id = $1", id), &h)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrHackathonNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (s *Service) Create(ctx context.Context, req *CreateRequest, userID int64, ip string) (*Hackathon, error) {
+	name := normalizeName(req.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	startDate, err := parseDate(req.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := parseDate(req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		return nil, ErrInvalidDates
+	}
+
+	var id int64
+	err = s.db.QueryRow(ctx, `INSERT INTO hackathons (name, organizer, city, is_online, start_date, end_date, url) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		name, nullable(req.Organizer), nullable(req.City), req.IsOnline, startDate, endDate, nullable(req.URL),
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrNameExists
+		}
+		return nil, fmt.Errorf("failed to create hackathon: %w", err)
+	}
+
+	h, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityHackathon, &h.ID, h, ip)
+	return h, nil
+}
+
+func (s *Service) Update(ctx context.Context, id int64, req *UpdateRequest, userID int64, ip string) (*Hackathon, error) {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	setParts := []string{}
+	args := []any{}
+	argNum := 1
+
+	if req.Name != nil {
+		name := normalizeName(*req.Name)
+		if name == "" {
+			return nil, ErrNameRequired
+		}
+		setParts = append(setParts, fmt.Sprintf("name = $%d", argNum))
+		args = append(args, name)
+		argNum++
+	}
+	if req.Organizer != nil {
+		setParts = append(setParts, fmt.Sprintf("organizer = $%d", argNum))
+		args = append(args, nullable(req.Organizer))
+		argNum++
+	}
+	if req.City != nil {
+		setParts = append(setParts, fmt.Sprintf("city = $%d", argNum))
+		args = append(args, nullable(req.City))
+		argNum++
+	}
+	if req.IsOnline != nil {
+		setParts = append(setParts, fmt.Sprintf("is_online = $%d", argNum))
+		args = append(args, *req.IsOnline)
+		argNum++
+	}
+	startDate, endDate := existing.StartDate, existing.EndDate
+	if req.StartDate != nil {
+		if startDate, err = parseDate(req.StartDate); err != nil {
+			return nil, err
+		}
+		setParts = append(setParts, fmt.Sprintf("start_date = $%d", argNum))
+		args = append(args, startDate)
+		argNum++
+	}
+	if req.EndDate != nil {
+		if endDate, err = parseDate(req.EndDate); err != nil {
+			return nil, err
+		}
+		setParts = append(setParts, fmt.Sprintf("end_date = $%d", argNum))
+		args = append(args, endDate)
+		argNum++
+	}
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		return nil, ErrInvalidDates
+	}
+	if req.URL != nil {
+		setParts = append(setParts, fmt.Sprintf("url = $%d", argNum))
+		args = append(args, nullable(req.URL))
+		argNum++
+	}
+
+	if len(setParts) == 0 {
+		return existing, nil
+	}
+
+	args = append(args, id)
+	_, err = s.db.Exec(ctx, fmt.Sprintf(`UPDATE hackathons SET %s WHERE id = $%d`, strings.Join(setParts, ", "), argNum), args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrNameExists
+		}
+		return nil, fmt.Errorf("failed to update hackathon: %w", err)
+	}
+
+	h, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityHackathon, &h.ID, map[string]any{"before": existing, "after": h}, ip)
+	return h, nil
+}
+
+// Delete removes a hackathon without wins
+func (s *Service) Delete(ctx context.Context, id int64, userID int64, ip string) error {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing.WinsCount > 0 {
+		return ErrHackathonInUse
+	}
+
+	_, err = s.db.Exec(ctx, "DELETE FROM hackathons WHERE id = $1", id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrHackathonInUse
+		}
+		return err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionDelete, audit.EntityHackathon, &id, existing, ip)
+	return nil
+}
+
+// Handler
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// writeError maps service errors to responses
+func writeError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, ErrHackathonNotFound):
+		response.NotFound(w, "hackathon not found")
+	case errors.Is(err, ErrNameRequired), errors.Is(err, ErrInvalidDate), errors.Is(err, ErrInvalidDates):
+		response.ValidationError(w, err.Error())
+	case errors.Is(err, ErrNameExists):
+		response.Conflict(w, err.Error())
+	case errors.Is(err, ErrHackathonInUse):
+		response.Conflict(w, "hackathon has wins, move or delete them first")
+	default:
+		slog.Error("failed to "+action+" hackathon", "error", err)
+		response.InternalError(w, "failed to "+action+" hackathon")
+	}
+}
+
+// List handles GET /api/hackathons
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	params := ListParams{Page: 1, PageSize: 20, Search: r.URL.Query().Get("search")}
+	if p, _ := strconv.Atoi(r.URL.Query().Get("page")); p > 0 {
+		params.Page = p
+	}
+	if ps, _ := strconv.Atoi(r.URL.Query().Get("page_size")); ps > 0 {
+		params.PageSize = ps
+	}
+
+	result, err := h.service.List(r.Context(), params)
+	if err != nil {
+		writeError(w, err, "list")
+		return
+	}
+	response.JSON(w, http.StatusOK, result)
+}
+
+// Get handles GET /api/hackathons/:id
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid hackathon id")
+		return
+	}
+
+	hackathon, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "get")
+		return
+	}
+	response.JSON(w, http.StatusOK, hackathon)
+}
+
+// Create handles POST /api/hackathons
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	hackathon, err := h.service.Create(r.Context(), &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeError(w, err, "create")
+		return
+	}
+	response.JSON(w, http.StatusCreated, hackathon)
+}
+
+// Update handles PUT /api/hackathons/:id
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid hackathon id")
+		return
+	}
+
+	var req UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	hackathon, err := h.service.Update(r.Context(), id, &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeError(w, err, "update")
+		return
+	}
+	response.JSON(w, http.StatusOK, hackathon)
+}
+
+// Delete handles DELETE /api/hackathons/:id
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid hackathon id")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.service.Delete(r.Context(), id, userID, r.RemoteAddr); err != nil {
+		writeError(w, err, "delete")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "hackathon deleted"})
+}
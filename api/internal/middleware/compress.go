@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/andybalholm/brotli"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// compressibleTypes are the content types worth compressing on the fly
+var compressibleTypes = []string{
+	"application/json",
+	"text/plain",
+	"text/csv",
+	"text/calendar",
+	"image/svg+xml",
+}
+
+// Compress negotiates brotli or gzip compression for API responses.
+// Responses that already carry a Content-Encoding (e.g. precompressed cache hits) are passed through untouched.
+func Compress(level int) func(next http.Handler) http.Handler {
+	compressor := chimiddleware.NewCompressor(level, compressibleTypes...)
+	compressor.SetEncoder("br", func(w io.Writer, level int) io.Writer {
+		return brotli.NewWriterLevel(w, level)
+	})
+	return compressor.Handler
+}
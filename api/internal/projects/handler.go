@@ -3,9 +3,13 @@ package projects
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/itam-misis/itam-api/internal/auth"
@@ -36,6 +40,7 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		v := pub == "true"
 		params.IsPublished = &v
 	}
+	params.Tags = tagSlugs(r)
 
 	result, err := h.service.List(r.Context(), params)
 	if err != nil {
@@ -46,9 +51,23 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, result)
 }
 
+// MaxTagFilter limits the tags of one public project filter
+const MaxTagFilter = 10
+
+// ListPublic handles GET /api/public/projects?tags=python,ml
 func (h *Handler) ListPublic(w http.ResponseWriter, r *http.Request) {
-	projects, err := h.service.ListPublic(r.Context())
+	slugs := tagSlugs(r)
+	if len(slugs) > MaxTagFilter {
+		response.ValidationError(w, fmt.Sprintf("at most %d tags", MaxTagFilter))
+		return
+	}
+
+	projects, err := h.service.ListPublic(r.Context(), slugs)
 	if err != nil {
+		if errors.Is(err, ErrTagNotFound) {
+			response.ValidationError(w, "unknown tag")
+			return
+		}
 		slog.Error("failed to list public projects", "error", err)
 		response.InternalError(w, "failed to list projects")
 		return
@@ -56,6 +75,47 @@ func (h *Handler) ListPublic(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, projects)
 }
 
+// PublicCacheKey builds the cache key of GET /api/public/projects from the parsed tag slugs,
+// so any order or spelling of the same tags shares an entry
+func PublicCacheKey(prefix string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		slugs := tagSlugs(r)
+		if len(slugs) == 0 {
+			return prefix
+		}
+		return prefix + ":" + url.Values{"tags": {strings.Join(slugs, ",")}}.Encode()
+	}
+}
+
+// tagSlugs reads the comma-separated tag slugs of the tags query parameter, deduplicated and sorted
+func tagSlugs(r *http.Request) []string {
+	var slugs []string
+	seen := map[string]bool{}
+	for _, s := range strings.Split(r.URL.Query().Get("tags"), ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			slugs = append(slugs, s)
+		}
+	}
+	slices.Sort(slugs)
+	return slugs
+}
+
+func (h *Handler) GetPublicBySlug(w http.ResponseWriter, r *http.Request) {
+	project, err := h.service.GetBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if errors.Is(err, ErrProjectNotFound) {
+		response.NotFound(w, "project not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get public project", "error", err)
+		response.InternalError(w, "failed to get project")
+		return
+	}
+	response.JSON(w, http.StatusOK, project)
+}
+
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
@@ -87,7 +147,9 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	project, err := h.service.Create(r.Context(), &req, userID, r.RemoteAddr)
 	if err != nil {
 		switch {
-		case errors.Is(err, ErrTitleRequired):
+		case errors.Is(err, ErrTitleRequired), errors.Is(err, ErrImageURLRequired):
+			response.ValidationError(w, err.Error())
+		case errors.Is(err, ErrWinNotFound), errors.Is(err, ErrMemberNotFound):
 			response.ValidationError(w, err.Error())
 		case errors.Is(err, ErrSlugExists):
 			response.Conflict(w, err.Error())
@@ -119,7 +181,9 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.Is(err, ErrProjectNotFound):
 			response.NotFound(w, "project not found")
-		case errors.Is(err, ErrTitleRequired):
+		case errors.Is(err, ErrTitleRequired), errors.Is(err, ErrImageURLRequired):
+			response.ValidationError(w, err.Error())
+		case errors.Is(err, ErrWinNotFound), errors.Is(err, ErrMemberNotFound):
 			response.ValidationError(w, err.Error())
 		case errors.Is(err, ErrSlugExists):
 			response.Conflict(w, err.Error())
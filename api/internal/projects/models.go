@@ -1,14 +1,19 @@
 package projects
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 )
 
 var (
-	ErrProjectNotFound = errors.New("project not found")
-	ErrTitleRequired   = errors.New("title is required")
-	ErrSlugExists      = errors.New("slug already exists")
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrTitleRequired    = errors.New("title is required")
+	ErrSlugExists       = errors.New("slug already exists")
+	ErrWinNotFound      = errors.New("win not found")
+	ErrMemberNotFound   = errors.New("team member not found")
+	ErrImageURLRequired = errors.New("gallery image url is required")
+	ErrTagNotFound      = errors.New("tag not found")
 )
 
 type Project struct {
@@ -17,6 +22,9 @@ type Project struct {
 	Slug        string    `json:"slug"`
 	Description *string   `json:"description"`
 	CoverImage  *string   `json:"cover_image"`
+	RepoURL     *string   `json:"repo_url"`
+	DemoURL     *string   `json:"demo_url"`
+	WinID       *int64    `json:"win_id"`
 	SortOrder   int       `json:"sort_order"`
 	IsPublished bool      `json:"is_published"`
 	Tags        []Tag     `json:"tags"`
@@ -24,9 +32,47 @@ type Project struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// ProjectDetail is a project with its page content, authors, gallery and related win
+type ProjectDetail struct {
+	Project
+	ContentJSON json.RawMessage `json:"content_json"`
+	ContentHTML *string         `json:"content_html"`
+	Authors     []Author        `json:"authors"`
+	Gallery     []Image         `json:"gallery"`
+	Win         *RelatedWin     `json:"win"`
+}
+
+// Author is a team member who made a project
+type Author struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Role  *string `json:"role"`
+	Photo *string `json:"photo"`
+}
+
+// Image is a gallery image
+type Image struct {
+	URL     string  `json:"url"`
+	Caption *string `json:"caption"`
+}
+
+// RelatedWin is the win a project was made for
+type RelatedWin struct {
+	ID            int64   `json:"id"`
+	TeamName      string  `json:"team_name"`
+	HackathonName string  `json:"hackathon_name"`
+	Result        string  `json:"result"`
+	Placement     *string `json:"placement"`
+	Rank          *int    `json:"rank"`
+	Year          int     `json:"year"`
+	AwardDate     *string `json:"award_date"`
+	Link          *string `json:"link"`
+}
+
 type Tag struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
+	Slug string `json:"slug"`
 }
 
 type CreateRequest struct {
@@ -38,13 +84,21 @@ type CreateRequest struct {
 	IsPublished bool     `json:"is_published"`
 	TagIDs      []int64  `json:"tag_ids"`
 	TagNames    []string `json:"tag_names"` // Create new tags by name
+
+	ContentJSON json.RawMessage `json:"content_json"`
+	ContentHTML *string         `json:"content_html"`
+	RepoURL     *string         `json:"repo_url"`
+	DemoURL     *string         `json:"demo_url"`
+	WinID       *int64          `json:"win_id"`
+	AuthorIDs   []int64         `json:"author_ids"` // Team member IDs in display order
+	Gallery     []Image         `json:"gallery"`
 }
 
 func (r *CreateRequest) Validate() error {
 	if r.Title == "" {
 		return ErrTitleRequired
 	}
-	return nil
+	return validateGallery(r.Gallery)
 }
 
 type UpdateRequest struct {
@@ -56,12 +110,32 @@ type UpdateRequest struct {
 	IsPublished *bool    `json:"is_published,omitempty"`
 	TagIDs      []int64  `json:"tag_ids,omitempty"`
 	TagNames    []string `json:"tag_names,omitempty"`
+
+	ContentJSON *json.RawMessage `json:"content_json,omitempty"`
+	ContentHTML *string          `json:"content_html,omitempty"`
+	RepoURL     *string          `json:"repo_url,omitempty"`   // Empty string removes the link
+	DemoURL     *string          `json:"demo_url,omitempty"`   // Empty string removes the link
+	WinID       *int64           `json:"win_id,omitempty"`     // 0 removes the related win
+	AuthorIDs   *[]int64         `json:"author_ids,omitempty"` // Replaces the authors
+	Gallery     *[]Image         `json:"gallery,omitempty"`    // Replaces the gallery
 }
 
 func (r *UpdateRequest) Validate() error {
 	if r.Title != nil && *r.Title == "" {
 		return ErrTitleRequired
 	}
+	if r.Gallery != nil {
+		return validateGallery(*r.Gallery)
+	}
+	return nil
+}
+
+func validateGallery(images []Image) error {
+	for _, img := range images {
+		if img.URL == "" {
+			return ErrImageURLRequired
+		}
+	}
 	return nil
 }
 
@@ -70,6 +144,7 @@ type ListParams struct {
 	PageSize    int
 	Search      string
 	IsPublished *bool
+	Tags        []string // Tag slugs; projects must have all of them
 }
 
 type ListResponse struct {
@@ -12,6 +12,7 @@ import (
 
 	"github.com/itam-misis/itam-api/internal/audit"
 	"github.com/itam-misis/itam-api/internal/slug"
+	"github.com/itam-misis/itam-api/internal/tags"
 )
 
 type Service struct {
@@ -23,6 +24,49 @@ func NewService(db *pgxpool.Pool, auditService *audit.Service) *Service {
 	return &Service{db: db, audit: auditService}
 }
 
+// projectColumns selects the fields shared by lists and detail pages; used with FROM projects p
+const projectColumns = `p.id, p.title, p.slug, p.description, p.cover_image, p.repo_url, p.demo_url, p.win_id,
+	p.sort_order, p.is_published, p.created_at, p.updated_at`
+
+// detailColumns adds the page content, authors, gallery and related win to projectColumns;
+// authorsFilter narrows the authors subquery
+func detailColumns(authorsFilter string) string {
+	return projectColumns + `, p.content_json, p.content_html,
+	COALESCE((
+		SELECT json_agg(json_build_object('id', tm.id, 'name', tm.name, 'role', tm.role, 'photo', tm.photo) ORDER BY pa.sort_order, tm.name)
+		FROM project_authors pa JOIN team_members tm ON tm.id = pa.team_member_id
+		WHERE pa.project_id = p.id` + authorsFilter + `
+	), '[]'),
+	COALESCE((
+		SELECT json_agg(json_build_object('url', pi.url, 'caption', pi.caption) ORDER BY pi.sort_order, pi.id)
+		FROM project_images pi
+		WHERE pi.project_id = p.id
+	), '[]'),
+	(
+		SELECT json_build_object('id', w.id, 'team_name', w.team_name, 'hackathon_name', h.name, 'result', w.result,
+			'placement', w.placement, 'rank', w.rank, 'year', w.year, 'award_date', w.award_date, 'link', w.link)
+		FROM wins w JOIN hackathons h ON h.id = w.hackathon_id
+		WHERE w.id = p.win_id
+	)`
+}
+
+func scanProject(row pgx.Row, p *Project) error {
+	return row.Scan(&p.ID, &p.Title, &p.Slug, &p.Description, &p.CoverImage, &p.RepoURL, &p.DemoURL, &p.WinID,
+		&p.SortOrder, &p.IsPublished, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func scanDetail(row pgx.Row, p *ProjectDetail) error {
+	return row.Scan(&p.ID, &p.Title, &p.Slug, &p.Description, &p.CoverImage, &p.RepoURL, &p.DemoURL, &p.WinID,
+		&p.SortOrder, &p.IsPublished, &p.CreatedAt, &p.UpdatedAt,
+		&p.ContentJSON, &p.ContentHTML, &p.Authors, &p.Gallery, &p.Win)
+}
+
+// querier is implemented by both the pool and transactions
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, error) {
 	if params.Page < 1 {
 		params.Page = 1
@@ -32,28 +76,34 @@ func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, e
 	}
 
 	offset := (params.Page - 1) * params.PageSize
-	baseQuery := "FROM projects WHERE 1=1"
+	baseQuery := "FROM projects p WHERE 1=1"
 	var args []any
 	argNum := 1
 
 	if params.Search != "" {
-		baseQuery += fmt.Sprintf(" AND (title ILIKE $%d OR description ILIKE $%d)", argNum, argNum)
+		baseQuery += fmt.Sprintf(" AND (p.title ILIKE $%d OR p.description ILIKE $%d)", argNum, argNum)
 		args = append(args, "%"+params.Search+"%")
 		argNum++
 	}
 
 	if params.IsPublished != nil {
-		baseQuery += fmt.Sprintf(" AND is_published = $%d", argNum)
+		baseQuery += fmt.Sprintf(" AND p.is_published = $%d", argNum)
 		args = append(args, *params.IsPublished)
 		argNum++
 	}
 
+	if len(params.Tags) > 0 {
+		baseQuery += fmt.Sprintf(" AND %s", tagFilter(argNum, argNum+1))
+		args = append(args, params.Tags, len(params.Tags))
+		argNum += 2
+	}
+
 	var total int
 	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total); err != nil {
 		return nil, err
 	}
 
-	query := fmt.Sprintf(`SELECT id, title, slug, description, cover_image, sort_order, is_published, created_at, updated_at %s ORDER BY sort_order DESC, created_at DESC LIMIT $%d OFFSET $%d`, baseQuery, argNum, argNum+1)
+	query := fmt.Sprintf(`SELECT %s %s ORDER BY p.sort_order DESC, p.created_at DESC LIMIT $%d OFFSET $%d`, projectColumns, baseQuery, argNum, argNum+1)
 	args = append(args, params.PageSize, offset)
 
 	rows, err := s.db.Query(ctx, query, args...)
@@ -65,7 +115,7 @@ func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, e
 	var projects []Project
 	for rows.Next() {
 		var p Project
-		if err := rows.Scan(&p.ID, &p.Title, &p.Slug, &p.Description, &p.CoverImage, &p.SortOrder, &p.IsPublished, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := scanProject(rows, &p); err != nil {
 			return nil, err
 		}
 		p.Tags, _ = s.getProjectTags(ctx, p.ID)
@@ -85,8 +135,35 @@ func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, e
 	}, nil
 }
 
-func (s *Service) ListPublic(ctx context.Context) ([]Project, error) {
-	rows, err := s.db.Query(ctx, `SELECT id, title, slug, description, cover_image, sort_order, is_published, created_at, updated_at FROM projects WHERE is_published = true ORDER BY sort_order DESC, created_at DESC`)
+// tagFilter matches projects that have all tags of a slug array; countArg is the length of the array
+func tagFilter(slugsArg, countArg int) string {
+	return fmt.Sprintf(`p.id IN (
+		SELECT pt.project_id FROM project_tags pt JOIN tags t ON t.id = pt.tag_id
+		WHERE t.slug = ANY($%d)
+		GROUP BY pt.project_id
+		HAVING COUNT(*) = $%d
+	)`, slugsArg, countArg)
+}
+
+// ListPublic returns the published projects, optionally only those with all of the given tag slugs.
+// Returns ErrTagNotFound when a slug matches no tag.
+func (s *Service) ListPublic(ctx context.Context, tagSlugs []string) ([]Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects p WHERE p.is_published = true`
+	var args []any
+	if len(tagSlugs) > 0 {
+		// Unknown tags are refused rather than listing nothing, so they are never cached
+		var known int
+		if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM tags WHERE slug = ANY($1)", tagSlugs).Scan(&known); err != nil {
+			return nil, err
+		}
+		if known != len(tagSlugs) {
+			return nil, ErrTagNotFound
+		}
+		query += " AND " + tagFilter(1, 2)
+		args = append(args, tagSlugs, len(tagSlugs))
+	}
+
+	rows, err := s.db.Query(ctx, query+` ORDER BY p.sort_order DESC, p.created_at DESC`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +172,7 @@ func (s *Service) ListPublic(ctx context.Context) ([]Project, error) {
 	var projects []Project
 	for rows.Next() {
 		var p Project
-		if err := rows.Scan(&p.ID, &p.Title, &p.Slug, &p.Description, &p.CoverImage, &p.SortOrder, &p.IsPublished, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := scanProject(rows, &p); err != nil {
 			return nil, err
 		}
 		p.Tags, _ = s.getProjectTags(ctx, p.ID)
@@ -108,9 +185,18 @@ func (s *Service) ListPublic(ctx context.Context) ([]Project, error) {
 	return projects, nil
 }
 
-func (s *Service) GetByID(ctx context.Context, id int64) (*Project, error) {
-	var p Project
-	err := s.db.QueryRow(ctx, `SELECT id, title, slug, description, cover_image, sort_order, is_published, created_at, updated_at FROM projects WHERE id = $1`, id).Scan(&p.ID, &p.Title, &p.Slug, &p.Description, &p.CoverImage, &p.SortOrder, &p.IsPublished, &p.CreatedAt, &p.UpdatedAt)
+func (s *Service) GetByID(ctx context.Context, id int64) (*ProjectDetail, error) {
+	return s.getDetail(ctx, detailColumns(""), "p.id = $1", id)
+}
+
+// GetBySlug returns a published project for its public page; hidden team members are left out of the authors
+func (s *Service) GetBySlug(ctx context.Context, projectSlug string) (*ProjectDetail, error) {
+	return s.getDetail(ctx, detailColumns(" AND tm.is_visible = true"), "p.slug = $1 AND p.is_published = true", projectSlug)
+}
+
+func (s *Service) getDetail(ctx context.Context, columns, where string, arg any) (*ProjectDetail, error) {
+	var p ProjectDetail
+	err := scanDetail(s.db.QueryRow(ctx, `SELECT `+columns+` FROM projects p WHERE `+where, arg), &p)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrProjectNotFound
 	}
@@ -121,7 +207,7 @@ func (s *Service) GetByID(ctx context.Context, id int64) (*Project, error) {
 	return &p, nil
 }
 
-func (s *Service) Create(ctx context.Context, req *CreateRequest, userID int64, ip string) (*Project, error) {
+func (s *Service) Create(ctx context.Context, req *CreateRequest, userID int64, ip string) (*ProjectDetail, error) {
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
@@ -131,30 +217,45 @@ func (s *Service) Create(ctx context.Context, req *CreateRequest, userID int64,
 		projectSlug = slug.Generate(req.Title)
 	}
 
-	var p Project
-	err := s.db.QueryRow(ctx, `INSERT INTO projects (title, slug, description, cover_image, sort_order, is_published) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, title, slug, description, cover_image, sort_order, is_published, created_at, updated_at`,
-		req.Title, projectSlug, req.Description, req.CoverImage, req.SortOrder, req.IsPublished,
-	).Scan(&p.ID, &p.Title, &p.Slug, &p.Description, &p.CoverImage, &p.SortOrder, &p.IsPublished, &p.CreatedAt, &p.UpdatedAt)
-
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return nil, ErrSlugExists
-		}
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `INSERT INTO projects (title, slug, description, cover_image, sort_order, is_published, content_json, content_html, repo_url, demo_url, win_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		req.Title, projectSlug, req.Description, req.CoverImage, req.SortOrder, req.IsPublished,
+		req.ContentJSON, req.ContentHTML, nullable(req.RepoURL), nullable(req.DemoURL), req.WinID,
+	).Scan(&id)
+	if err != nil {
+		return nil, projectError(err)
+	}
 
 	// Handle tags
-	if err := s.syncTags(ctx, p.ID, req.TagIDs, req.TagNames); err != nil {
+	if err := syncTags(ctx, tx, id, req.TagIDs, req.TagNames); err != nil {
+		return nil, err
+	}
+	if err := setAuthors(ctx, tx, id, req.AuthorIDs); err != nil {
+		return nil, err
+	}
+	if err := setGallery(ctx, tx, id, req.Gallery); err != nil {
 		return nil, err
 	}
 
-	p.Tags, _ = s.getProjectTags(ctx, p.ID)
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	p, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityProject, &p.ID, p, ip)
-	return &p, nil
+	return p, nil
 }
 
-func (s *Service) Update(ctx context.Context, id int64, req *UpdateRequest, userID int64, ip string) (*Project, error) {
+func (s *Service) Update(ctx context.Context, id int64, req *UpdateRequest, userID int64, ip string) (*ProjectDetail, error) {
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
@@ -198,21 +299,48 @@ func (s *Service) Update(ctx context.Context, id int64, req *UpdateRequest, user
 		args = append(args, *req.IsPublished)
 		argNum++
 	}
+	if req.ContentJSON != nil {
+		setParts = append(setParts, fmt.Sprintf("content_json = $%d", argNum))
+		args = append(args, *req.ContentJSON)
+		argNum++
+	}
+	if req.ContentHTML != nil {
+		setParts = append(setParts, fmt.Sprintf("content_html = $%d", argNum))
+		args = append(args, *req.ContentHTML)
+		argNum++
+	}
+	if req.RepoURL != nil {
+		setParts = append(setParts, fmt.Sprintf("repo_url = $%d", argNum))
+		args = append(args, nullable(req.RepoURL))
+		argNum++
+	}
+	if req.DemoURL != nil {
+		setParts = append(setParts, fmt.Sprintf("demo_url = $%d", argNum))
+		args = append(args, nullable(req.DemoURL))
+		argNum++
+	}
+	if req.WinID != nil {
+		setParts = append(setParts, fmt.Sprintf("win_id = $%d", argNum))
+		if *req.WinID == 0 {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.WinID)
+		}
+		argNum++
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	var p Project
 	if len(setParts) > 0 {
 		args = append(args, id)
-		query := fmt.Sprintf(`UPDATE projects SET %s WHERE id = $%d RETURNING id, title, slug, description, cover_image, sort_order, is_published, created_at, updated_at`, strings.Join(setParts, ", "), argNum)
-		err = s.db.QueryRow(ctx, query, args...).Scan(&p.ID, &p.Title, &p.Slug, &p.Description, &p.CoverImage, &p.SortOrder, &p.IsPublished, &p.CreatedAt, &p.UpdatedAt)
-		if err != nil {
-			var pgErr *pgconn.PgError
-			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-				return nil, ErrSlugExists
-			}
-			return nil, err
+		query := fmt.Sprintf(`UPDATE projects SET %s WHERE id = $%d`, strings.Join(setParts, ", "), argNum)
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return nil, projectError(err)
 		}
-	} else {
-		p = *existing
 	}
 
 	// Handle tags if provided
@@ -225,14 +353,31 @@ func (s *Service) Update(ctx context.Context, id int64, req *UpdateRequest, user
 		if tagNames == nil {
 			tagNames = []string{}
 		}
-		if err := s.syncTags(ctx, p.ID, tagIDs, tagNames); err != nil {
+		if err := syncTags(ctx, tx, id, tagIDs, tagNames); err != nil {
+			return nil, err
+		}
+	}
+	if req.AuthorIDs != nil {
+		if err := setAuthors(ctx, tx, id, *req.AuthorIDs); err != nil {
+			return nil, err
+		}
+	}
+	if req.Gallery != nil {
+		if err := setGallery(ctx, tx, id, *req.Gallery); err != nil {
 			return nil, err
 		}
 	}
 
-	p.Tags, _ = s.getProjectTags(ctx, p.ID)
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	p, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityProject, &p.ID, map[string]any{"before": existing, "after": p}, ip)
-	return &p, nil
+	return p, nil
 }
 
 func (s *Service) Delete(ctx context.Context, id int64, userID int64, ip string) error {
@@ -262,7 +407,7 @@ func (s *Service) Reorder(ctx context.Context, ids []int64, userID int64, ip str
 }
 
 func (s *Service) getProjectTags(ctx context.Context, projectID int64) ([]Tag, error) {
-	rows, err := s.db.Query(ctx, `SELECT t.id, t.name FROM tags t JOIN project_tags pt ON t.id = pt.tag_id WHERE pt.project_id = $1 ORDER BY t.name`, projectID)
+	rows, err := s.db.Query(ctx, `SELECT t.id, t.name, t.slug FROM tags t JOIN project_tags pt ON t.id = pt.tag_id WHERE pt.project_id = $1 ORDER BY t.name`, projectID)
 	if err != nil {
 		return nil, err
 	}
@@ -271,7 +416,7 @@ func (s *Service) getProjectTags(ctx context.Context, projectID int64) ([]Tag, e
 	var tags []Tag
 	for rows.Next() {
 		var t Tag
-		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+		if err := rows.Scan(&t.ID, &t.Name, &t.Slug); err != nil {
 			return nil, err
 		}
 		tags = append(tags, t)
@@ -282,17 +427,19 @@ func (s *Service) getProjectTags(ctx context.Context, projectID int64) ([]Tag, e
 	return tags, nil
 }
 
-func (s *Service) syncTags(ctx context.Context, projectID int64, tagIDs []int64, tagNames []string) error {
+func syncTags(ctx context.Context, q querier, projectID int64, tagIDs []int64, tagNames []string) error {
 	// Delete existing
-	_, err := s.db.Exec(ctx, "DELETE FROM project_tags WHERE project_id = $1", projectID)
+	_, err := q.Exec(ctx, "DELETE FROM project_tags WHERE project_id = $1", projectID)
 	if err != nil {
 		return err
 	}
 
-	// Create new tags by name
+	// Create new tags by name, reusing tags that differ only in case
 	for _, name := range tagNames {
-		var tagID int64
-		err := s.db.QueryRow(ctx, `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING id`, name).Scan(&tagID)
+		if tags.Normalize(name) == "" {
+			continue
+		}
+		tagID, err := tags.Resolve(ctx, q, name)
 		if err != nil {
 			return err
 		}
@@ -301,7 +448,7 @@ func (s *Service) syncTags(ctx context.Context, projectID int64, tagIDs []int64,
 
 	// Insert project_tags
 	for _, tagID := range tagIDs {
-		_, err := s.db.Exec(ctx, "INSERT INTO project_tags (project_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", projectID, tagID)
+		_, err := q.Exec(ctx, "INSERT INTO project_tags (project_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", projectID, tagID)
 		if err != nil {
 			return err
 		}
@@ -310,9 +457,65 @@ func (s *Service) syncTags(ctx context.Context, projectID int64, tagIDs []int64,
 	return nil
 }
 
+// setAuthors replaces the authors of a project, keeping the given order
+func setAuthors(ctx context.Context, q querier, projectID int64, memberIDs []int64) error {
+	if _, err := q.Exec(ctx, "DELETE FROM project_authors WHERE project_id = $1", projectID); err != nil {
+		return err
+	}
+
+	for i, memberID := range memberIDs {
+		_, err := q.Exec(ctx, "INSERT INTO project_authors (project_id, team_member_id, sort_order) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", projectID, memberID, i)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				return ErrMemberNotFound
+			}
+			return fmt.Errorf("failed to add author: %w", err)
+		}
+	}
+	return nil
+}
+
+// setGallery replaces the gallery of a project, keeping the given order
+func setGallery(ctx context.Context, q querier, projectID int64, images []Image) error {
+	if _, err := q.Exec(ctx, "DELETE FROM project_images WHERE project_id = $1", projectID); err != nil {
+		return err
+	}
+
+	for i, img := range images {
+		_, err := q.Exec(ctx, "INSERT INTO project_images (project_id, url, caption, sort_order) VALUES ($1, $2, $3, $4)", projectID, img.URL, nullable(img.Caption), i)
+		if err != nil {
+			return fmt.Errorf("failed to add gallery image: %w", err)
+		}
+	}
+	return nil
+}
+
+// projectError maps constraint violations of the projects table
+func projectError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrSlugExists
+		case "23503":
+			return ErrWinNotFound
+		}
+	}
+	return err
+}
+
+// nullable stores empty strings as NULL
+func nullable(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
 // Tags CRUD
 func (s *Service) ListTags(ctx context.Context) ([]Tag, error) {
-	rows, err := s.db.Query(ctx, "SELECT id, name FROM tags ORDER BY name")
+	rows, err := s.db.Query(ctx, "SELECT id, name, slug FROM tags ORDER BY LOWER(name)")
 	if err != nil {
 		return nil, err
 	}
@@ -321,7 +524,7 @@ func (s *Service) ListTags(ctx context.Context) ([]Tag, error) {
 	var tags []Tag
 	for rows.Next() {
 		var t Tag
-		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+		if err := rows.Scan(&t.ID, &t.Name, &t.Slug); err != nil {
 			return nil, err
 		}
 		tags = append(tags, t)
@@ -26,6 +26,7 @@ const (
 	ErrCodeConflict       = "CONFLICT"
 	ErrCodeInternal       = "INTERNAL_ERROR"
 	ErrCodeValidation     = "VALIDATION_ERROR"
+	ErrCodeQuotaExceeded  = "QUOTA_EXCEEDED"
 )
 
 // JSON sends a successful response with data
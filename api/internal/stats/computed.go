@@ -0,0 +1,128 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+var (
+	ErrUnknownSource = errors.New("unknown stat source")
+	ErrInvalidFormat = errors.New("invalid format template")
+)
+
+// defaultCurrency is the prize currency of wins_prize_sum
+const defaultCurrency = "RUB"
+
+// Source computes the value of a stat from live data
+type Source func(ctx context.Context, params SourceParams) (float64, error)
+
+// SourceParams narrows what a source counts
+type SourceParams struct {
+	Year        int    `json:"year,omitempty"`         // Only wins of this year
+	CurrentYear bool   `json:"current_year,omitempty"` // Only wins of the current year
+	Currency    string `json:"currency,omitempty"`     // Prize currency, RUB by default
+}
+
+func (p SourceParams) year() int {
+	if p.CurrentYear {
+		return time.Now().Year()
+	}
+	return p.Year
+}
+
+func (p SourceParams) currency() string {
+	if p.Currency == "" {
+		return defaultCurrency
+	}
+	return p.Currency
+}
+
+// builtinSources registers the sources backed by the database
+func (s *Service) builtinSources() {
+	yearArgs := func(p SourceParams) []any { return []any{p.year()} }
+	noArgs := func(SourceParams) []any { return nil }
+
+	s.sources = map[string]Source{
+		"wins_count": s.querySource("SELECT COUNT(*)::float8 FROM wins WHERE ($1::int = 0 OR year = $1)", yearArgs),
+		"wins_prize_sum": s.querySource(
+			"SELECT COALESCE(SUM(prize), 0)::float8 FROM wins WHERE ($1::int = 0 OR year = $1) AND currency = $2",
+			func(p SourceParams) []any { return []any{p.year(), p.currency()} },
+		),
+		"wins_years":       s.querySource("SELECT COUNT(DISTINCT year)::float8 FROM wins", noArgs),
+		"hackathons_count": s.querySource("SELECT COUNT(DISTINCT hackathon_id)::float8 FROM wins WHERE ($1::int = 0 OR year = $1)", yearArgs),
+		"clubs_count":      s.querySource("SELECT COUNT(*)::float8 FROM clubs WHERE is_visible = true", noArgs),
+		"partners_count":   s.querySource("SELECT COUNT(*)::float8 FROM partners WHERE is_visible = true", noArgs),
+		"projects_count":   s.querySource("SELECT COUNT(*)::float8 FROM projects WHERE is_published = true", noArgs),
+		"team_count":       s.querySource("SELECT COUNT(*)::float8 FROM team_members WHERE is_visible = true", noArgs),
+	}
+}
+
+func (s *Service) querySource(query string, args func(SourceParams) []any) Source {
+	return func(ctx context.Context, params SourceParams) (float64, error) {
+		var value float64
+		if err := s.db.QueryRow(ctx, query, args(params)...).Scan(&value); err != nil {
+			return 0, err
+		}
+		return value, nil
+	}
+}
+
+// RegisterSource adds a source backed by another service, e.g. Telegram subscribers
+func (s *Service) RegisterSource(name string, source Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources[name] = source
+}
+
+// Sources returns the names of all sources
+func (s *Service) Sources() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Service) source(name string) (Source, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source, ok := s.sources[name]
+	return source, ok
+}
+
+// compute evaluates a computed stat and formats it
+func (s *Service) compute(ctx context.Context, st *Stat) (string, error) {
+	source, ok := s.source(*st.Source)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownSource, *st.Source)
+	}
+	value, err := source(ctx, st.Params)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute %s: %w", st.Key, err)
+	}
+	var template string
+	if st.Format != nil {
+		template = *st.Format
+	}
+	return formatValue(template, value)
+}
+
+// Sources handles GET /api/stats/sources
+func (h *Handler) Sources(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, h.service.Sources())
+}
+
+// logComputeError reports a source that failed; the last stored value is served instead
+func logComputeError(st *Stat, err error) {
+	slog.Warn("failed to compute stat, serving stored value", "key", st.Key, "error", err)
+}
@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultFormat prints the value as an integer
+const DefaultFormat = "{value}"
+
+// placeholder matches {value} with optional filters: {value|floor:100|group:.}
+var placeholder = regexp.MustCompile(`\{value((?:\|[^|{}]+)*)\}`)
+
+// number is a value being formatted
+type number struct {
+	value    float64
+	decimals int
+	sep      string // Thousands separator, none if empty
+}
+
+// apply runs one template filter:
+//
+//	floor:N     round down to a multiple of N, e.g. 5543 → 5500 with floor:100
+//	round[:N]   round to the nearest multiple of N, 1 by default
+//	k, mln      divide by a thousand or a million
+//	decimals:D  print D decimals (0-3) with a decimal comma
+//	group[:SEP] separate thousands with SEP, a space by default
+func (n *number) apply(filter string) error {
+	name, arg, hasArg := strings.Cut(filter, ":")
+	switch name {
+	case "floor", "round":
+		step := 1.0
+		if hasArg || name == "floor" {
+			var err error
+			step, err = strconv.ParseFloat(arg, 64)
+			if err != nil || step <= 0 {
+				return fmt.Errorf("%w: %s needs a positive step", ErrInvalidFormat, name)
+			}
+		}
+		if name == "floor" {
+			n.value = math.Floor(n.value/step) * step
+		} else {
+			n.value = math.Round(n.value/step) * step
+		}
+	case "k":
+		n.value /= 1e3
+	case "mln":
+		n.value /= 1e6
+	case "decimals":
+		d, err := strconv.Atoi(arg)
+		if err != nil || d < 0 || d > 3 {
+			return fmt.Errorf("%w: decimals must be between 0 and 3", ErrInvalidFormat)
+		}
+		n.decimals = d
+	case "group":
+		n.sep = " "
+		if hasArg {
+			n.sep = arg
+		}
+	default:
+		return fmt.Errorf("%w: unknown filter %q", ErrInvalidFormat, name)
+	}
+	return nil
+}
+
+func (n *number) String() string {
+	s := strconv.FormatFloat(n.value, 'f', n.decimals, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+
+	if n.sep != "" && len(whole) > 3 {
+		var b strings.Builder
+		for i, d := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(n.sep)
+			}
+			b.WriteRune(d)
+		}
+		whole = b.String()
+	}
+
+	if frac != "" {
+		return sign + whole + "," + frac
+	}
+	return sign + whole
+}
+
+// formatValue renders a computed value with a template like "~{value|mln} млн"
+func formatValue(template string, value float64) (string, error) {
+	if template == "" {
+		template = DefaultFormat
+	}
+	if !placeholder.MatchString(template) {
+		return "", fmt.Errorf("%w: no {value} placeholder", ErrInvalidFormat)
+	}
+
+	var err error
+	out := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		filters := placeholder.FindStringSubmatch(match)[1]
+		n := number{value: value}
+		for _, filter := range strings.Split(filters, "|")[1:] {
+			if ferr := n.apply(filter); ferr != nil {
+				err = ferr
+				return match
+			}
+		}
+		return n.String()
+	})
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -21,22 +23,44 @@ var (
 	ErrValueRequired  = errors.New("value is required")
 )
 
+// Stat is a landing figure. Computed stats (with a source) are evaluated from live data
+// and formatted by Format unless Override is set; Value then holds the hand-typed figure,
+// otherwise the last computed one.
 type Stat struct {
-	ID        int64     `json:"id"`
-	Key       string    `json:"key"`
-	Value     string    `json:"value"`
-	Label     *string   `json:"label"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int64        `json:"id"`
+	Key       string       `json:"key"`
+	Value     string       `json:"value"`
+	Label     *string      `json:"label"`
+	Source    *string      `json:"source"`
+	Params    SourceParams `json:"params"`
+	Format    *string      `json:"format"`
+	Override  bool         `json:"override"`
+	Computed  *string      `json:"computed,omitempty"` // Live value of a computed stat, also when overridden
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
+const statColumns = "id, key, value, label, source, params, format, override, updated_at"
+
+func scanStat(row pgx.Row, st *Stat) error {
+	return row.Scan(&st.ID, &st.Key, &st.Value, &st.Label, &st.Source, &st.Params, &st.Format, &st.Override, &st.UpdatedAt)
+}
+
+// UpdateRequest changes a stat. A value given for a computed stat overrides it
+// unless override is false; "override": false returns to the computed value.
 type UpdateRequest struct {
-	Value string  `json:"value"`
-	Label *string `json:"label,omitempty"`
+	Value    string        `json:"value"`
+	Label    *string       `json:"label,omitempty"`
+	Source   *string       `json:"source,omitempty"` // Empty makes the stat manual
+	Params   *SourceParams `json:"params,omitempty"`
+	Format   *string       `json:"format,omitempty"`
+	Override *bool         `json:"override,omitempty"`
 }
 
 func (r *UpdateRequest) Validate() error {
-	if r.Value == "" {
-		return ErrValueRequired
+	if r.Format != nil && *r.Format != "" {
+		if _, err := formatValue(*r.Format, 0); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -45,14 +69,19 @@ func (r *UpdateRequest) Validate() error {
 type Service struct {
 	db    *pgxpool.Pool
 	audit *audit.Service
+
+	mu      sync.Mutex
+	sources map[string]Source
 }
 
 func NewService(db *pgxpool.Pool, auditService *audit.Service) *Service {
-	return &Service{db: db, audit: auditService}
+	s := &Service{db: db, audit: auditService}
+	s.builtinSources()
+	return s
 }
 
 func (s *Service) List(ctx context.Context) ([]Stat, error) {
-	rows, err := s.db.Query(ctx, "SELECT id, key, value, label, updated_at FROM stats ORDER BY key")
+	rows, err := s.db.Query(ctx, "SELECT "+statColumns+" FROM stats ORDER BY key")
 	if err != nil {
 		return nil, err
 	}
@@ -61,34 +90,128 @@ func (s *Service) List(ctx context.Context) ([]Stat, error) {
 	var stats []Stat
 	for rows.Next() {
 		var st Stat
-		rows.Scan(&st.ID, &st.Key, &st.Value, &st.Label, &st.UpdatedAt)
+		if err := scanStat(rows, &st); err != nil {
+			return nil, err
+		}
 		stats = append(stats, st)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		if stats[i].Source == nil {
+			continue
+		}
+		computed, err := s.compute(ctx, &stats[i])
+		if err != nil {
+			logComputeError(&stats[i], err)
+			continue
+		}
+		stats[i].Computed = &computed
+	}
+
 	if stats == nil {
 		stats = []Stat{}
 	}
 	return stats, nil
 }
 
+// GetPublic returns all stats by key with computed stats evaluated.
+// A source that fails serves the last stored value; nothing is memoized or written here,
+// the route caches the response in Redis and StoreComputed keeps stored values current.
 func (s *Service) GetPublic(ctx context.Context) (map[string]string, error) {
-	rows, err := s.db.Query(ctx, "SELECT key, value FROM stats")
+	stats, err := s.loadAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(stats))
+	for i := range stats {
+		st := &stats[i]
+		result[st.Key] = st.Value
+		if st.Source == nil || st.Override {
+			continue
+		}
+
+		value, err := s.compute(ctx, st)
+		if err != nil {
+			logComputeError(st, err)
+			continue
+		}
+		result[st.Key] = value
+	}
+
+	return result, nil
+}
+
+// StoreComputed saves the current value of every computed stat that is not overridden,
+// as the fallback when its source fails and for the admin list
+func (s *Service) StoreComputed(ctx context.Context) error {
+	stats, err := s.loadAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range stats {
+		st := &stats[i]
+		if st.Source == nil || st.Override {
+			continue
+		}
+		value, err := s.compute(ctx, st)
+		if err != nil {
+			logComputeError(st, err)
+			continue
+		}
+		if value == st.Value {
+			continue
+		}
+		if _, err := s.db.Exec(ctx, "UPDATE stats SET value = $1, updated_at = NOW() WHERE id = $2 AND NOT override", value, st.ID); err != nil {
+			return fmt.Errorf("failed to store computed stat %s: %w", st.Key, err)
+		}
+	}
+	return nil
+}
+
+// RunStoreJob stores computed values on start and then every interval until ctx is done
+func (s *Service) RunStoreJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.StoreComputed(ctx); err != nil {
+			slog.Error("failed to store computed stats", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) loadAll(ctx context.Context) ([]Stat, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+statColumns+" FROM stats")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	result := make(map[string]string)
+	var stats []Stat
 	for rows.Next() {
-		var key, value string
-		rows.Scan(&key, &value)
-		result[key] = value
+		var st Stat
+		if err := scanStat(rows, &st); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
 	}
-	return result, nil
+	return stats, rows.Err()
 }
 
 func (s *Service) GetByKey(ctx context.Context, key string) (*Stat, error) {
 	var st Stat
-	err := s.db.QueryRow(ctx, "SELECT id, key, value, label, updated_at FROM stats WHERE key = $1", key).Scan(&st.ID, &st.Key, &st.Value, &st.Label, &st.UpdatedAt)
+	err := scanStat(s.db.QueryRow(ctx, "SELECT "+statColumns+" FROM stats WHERE key = $1", key), &st)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrStatNotFound
 	}
@@ -105,16 +228,71 @@ func (s *Service) Update(ctx context.Context, key string, req *UpdateRequest, us
 		return nil, err
 	}
 
-	var st Stat
-	if req.Label != nil {
-		err = s.db.QueryRow(ctx, "UPDATE stats SET value = $1, label = $2, updated_at = NOW() WHERE key = $3 RETURNING id, key, value, label, updated_at", req.Value, *req.Label, key).Scan(&st.ID, &st.Key, &st.Value, &st.Label, &st.UpdatedAt)
+	source := existing.Source
+	if req.Source != nil {
+		source = nil
+		if *req.Source != "" {
+			if _, ok := s.source(*req.Source); !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownSource, *req.Source)
+			}
+			source = req.Source
+		}
+	}
+
+	params := existing.Params
+	if req.Params != nil {
+		params = *req.Params
+	}
+
+	format := existing.Format
+	if req.Format != nil {
+		format = nil
+		if *req.Format != "" {
+			format = req.Format
+		}
+	}
+
+	// A value typed for a computed stat overrides it
+	override := existing.Override
+	if req.Override != nil {
+		override = *req.Override
+	} else if req.Value != "" && source != nil {
+		override = true
+	}
+	if source == nil {
+		override = false
+	}
+
+	value := req.Value
+	if source == nil || override {
+		if value == "" {
+			return nil, ErrValueRequired
+		}
 	} else {
-		err = s.db.QueryRow(ctx, "UPDATE stats SET value = $1, updated_at = NOW() WHERE key = $2 RETURNING id, key, value, label, updated_at", req.Value, key).Scan(&st.ID, &st.Key, &st.Value, &st.Label, &st.UpdatedAt)
+		value = existing.Value
 	}
+
+	var st Stat
+	err = scanStat(s.db.QueryRow(ctx, `
+		UPDATE stats
+		SET value = $1, label = COALESCE($2, label), source = $3, params = $4, format = $5, override = $6, updated_at = NOW()
+		WHERE key = $7
+		RETURNING `+statColumns,
+		value, req.Label, source, params, format, override, key,
+	), &st)
 	if err != nil {
 		return nil, err
 	}
 
+	if st.Source != nil {
+		computed, err := s.compute(ctx, &st)
+		if err != nil {
+			logComputeError(&st, err)
+		} else {
+			st.Computed = &computed
+		}
+	}
+
 	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityStat, &st.ID, map[string]any{"before": existing, "after": st}, ip)
 	return &st, nil
 }
@@ -161,7 +339,7 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		response.NotFound(w, "stat not found")
 		return
 	}
-	if errors.Is(err, ErrValueRequired) {
+	if errors.Is(err, ErrValueRequired) || errors.Is(err, ErrUnknownSource) || errors.Is(err, ErrInvalidFormat) {
 		response.ValidationError(w, err.Error())
 		return
 	}
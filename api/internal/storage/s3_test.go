@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an in-memory stand-in for an S3-compatible server (path-style requests, no signature checks)
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]fakeObject
+}
+
+type fakeObject struct {
+	data         []byte
+	contentType  string
+	cacheControl string
+	modified     time.Time
+}
+
+func newFakeS3(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(&fakeS3{buckets: map[string]map[string]fakeObject{}})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	objects, exists := f.buckets[bucket]
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodHead && exists:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			f.buckets[bucket] = map[string]fakeObject{}
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" && exists:
+			f.list(w, bucket, objects, r.URL.Query().Get("prefix"))
+		default:
+			s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		}
+		return
+	}
+	if !exists {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := readPayload(r)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		objects[key] = fakeObject{
+			data:         data,
+			contentType:  r.Header.Get("Content-Type"),
+			cacheControl: r.Header.Get("Cache-Control"),
+			modified:     time.Now().UTC().Truncate(time.Second),
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		obj, ok := objects[key]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Cache-Control", obj.cacheControl)
+		w.Header().Set("Last-Modified", obj.modified.Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, bucket string, objects map[string]fakeObject, prefix string) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+		StorageClass string
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []content
+	}{Name: bucket, Prefix: prefix, MaxKeys: 1000}
+
+	for key, obj := range objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, content{
+				Key:          key,
+				LastModified: obj.modified.Format(time.RFC3339),
+				ETag:         `"etag"`,
+				Size:         len(obj.data),
+				StorageClass: "STANDARD",
+			})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// readPayload reads a PUT body, decoding aws-chunked bodies sent with streaming signatures over plain HTTP
+func readPayload(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data bytes.Buffer
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data.Bytes(), nil
+		}
+		if _, err := io.CopyN(&data, br, size); err != nil {
+			return nil, err
+		}
+		if _, err := br.Discard(2); err != nil { // CRLF after the chunk
+			return nil, err
+		}
+	}
+}
+
+func newTestS3(t *testing.T, publicEndpoint string) *S3 {
+	t.Helper()
+	srv := newFakeS3(t)
+	s, err := NewS3(context.Background(), S3Config{
+		Endpoint:       strings.TrimPrefix(srv.URL, "http://"),
+		Bucket:         "itam-uploads",
+		AccessKey:      "access",
+		SecretKey:      "secret",
+		PublicEndpoint: publicEndpoint,
+	})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	return s
+}
+
+func TestS3ObjectLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := newTestS3(t, "")
+
+	content := []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>")
+	if err := s.Put(ctx, "svg/logo.svg", bytes.NewReader(content), int64(len(content)), "image/svg+xml"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put(ctx, "images/photo.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	obj, err := s.Stat(ctx, "svg/logo.svg")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if obj.Size != int64(len(content)) {
+		t.Errorf("Stat size = %d, want %d", obj.Size, len(content))
+	}
+
+	rc, err := s.Get(ctx, "svg/logo.svg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("read object: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Get = %q, want %q", got, content)
+	}
+
+	objects, err := s.List(ctx, "svg/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "svg/logo.svg" {
+		t.Errorf("List(svg/) = %+v, want only svg/logo.svg", objects)
+	}
+
+	if err := s.Delete(ctx, "svg/logo.svg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Stat(ctx, "svg/logo.svg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat after Delete: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Get(ctx, "svg/logo.svg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "svg/logo.svg"); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestS3RejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	s := newTestS3(t, "")
+
+	for _, key := range []string{"", "/images/a.jpg", "../a.jpg", "images/../../a.jpg", `images\a.jpg`} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q): err = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestS3PresignPutIsBound(t *testing.T) {
+	s := newTestS3(t, "uploads.example.com")
+
+	raw, err := s.PresignPut(context.Background(), "incoming/0123", 15*time.Minute, 2048, "image/png")
+	if err != nil {
+		t.Fatalf("PresignPut: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse presigned URL: %v", err)
+	}
+
+	if u.Host != "uploads.example.com" {
+		t.Errorf("host = %q, want the public endpoint", u.Host)
+	}
+	if u.Path != "/itam-uploads/incoming/0123" {
+		t.Errorf("path = %q", u.Path)
+	}
+	signed := strings.Split(u.Query().Get("X-Amz-SignedHeaders"), ";")
+	for _, header := range []string{"content-length", "content-type", "host"} {
+		found := false
+		for _, h := range signed {
+			found = found || h == header
+		}
+		if !found {
+			t.Errorf("signed headers %v do not include %s", signed, header)
+		}
+	}
+}
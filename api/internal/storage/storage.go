@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound           = errors.New("object not found")
+	ErrInvalidKey         = errors.New("invalid object key")
+	ErrPresignUnsupported = errors.New("presigned uploads are not supported by this storage")
+)
+
+// Drivers
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Object describes a stored file
+type Object struct {
+	Key        string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// Storage stores uploaded files under slash-separated keys such as "images/<hash>.jpg"
+type Storage interface {
+	// Put stores the content under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object for reading; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat returns ErrNotFound when the object does not exist
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// PresignPut returns a URL the client can PUT the object to directly. The signature covers
+	// the size and content type, so the upload must send exactly these Content-Length and Content-Type.
+	PresignPut(ctx context.Context, key string, expiry time.Duration, size int64, contentType string) (string, error)
+}
+
+// Config selects and configures a storage driver
+type Config struct {
+	Driver    string
+	LocalPath string
+	S3        S3Config
+}
+
+// New creates the storage configured by cfg
+func New(ctx context.Context, cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		return NewLocal(cfg.LocalPath)
+	case DriverS3:
+		return NewS3(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Driver)
+	}
+}
+
+// cleanKey validates a key so it can never escape the storage root
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,499 @@
+package tags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/itam-misis/itam-api/internal/audit"
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+	"github.com/itam-misis/itam-api/internal/slug"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTagNotFound    = errors.New("tag not found")
+	ErrNameRequired   = errors.New("name is required")
+	ErrTagExists      = errors.New("tag with this name already exists")
+	ErrTagInUse       = errors.New("tag is in use")
+	ErrSourceRequired = errors.New("source_ids are required")
+	ErrTargetRequired = errors.New("target_id is required")
+	ErrMergeIntoSelf  = errors.New("target_id cannot be one of source_ids")
+)
+
+// maxSlugLength keeps generated slugs within the column with room for a numeric suffix
+const maxSlugLength = 200
+
+// Tag is a tag with the number of projects and events using it
+type Tag struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Slug          string `json:"slug"`
+	ProjectsCount int    `json:"projects_count"`
+	EventsCount   int    `json:"events_count"`
+	UsageCount    int    `json:"usage_count"`
+}
+
+type RenameRequest struct {
+	Name string `json:"name"`
+}
+
+// MergeRequest moves all uses of the source tags to the target tag and deletes the sources
+type MergeRequest struct {
+	SourceIDs []int64 `json:"source_ids"`
+	TargetID  int64   `json:"target_id"`
+}
+
+func (r *MergeRequest) Validate() error {
+	if r.TargetID == 0 {
+		return ErrTargetRequired
+	}
+	if len(r.SourceIDs) == 0 {
+		return ErrSourceRequired
+	}
+
+	seen := make(map[int64]bool, len(r.SourceIDs))
+	ids := r.SourceIDs[:0]
+	for _, id := range r.SourceIDs {
+		if id == r.TargetID {
+			return ErrMergeIntoSelf
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	r.SourceIDs = ids
+	return nil
+}
+
+type ListParams struct {
+	Search string
+	Unused bool // Only tags without projects and events
+}
+
+// DeleteUnusedResult lists the tags removed by DeleteUnused
+type DeleteUnusedResult struct {
+	Deleted int   `json:"deleted"`
+	Tags    []Tag `json:"tags"`
+}
+
+// Normalize trims and collapses whitespace; names are unique case-insensitively
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Slug makes the URL slug of a tag name, spelling out the symbols of names like C++ and C#
+func Slug(name string) string {
+	s := slug.Generate(strings.NewReplacer("+", "-plus", "#", "-sharp").Replace(strings.ToLower(Normalize(name))))
+	if len(s) > maxSlugLength {
+		s = strings.Trim(s[:maxSlugLength], "-")
+	}
+	return s
+}
+
+// Querier is implemented by both the pool and transactions
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Resolve returns the ID of the tag with the given name, matched case-insensitively,
+// creating the tag if there is none. Projects and events use it for tags given by name.
+func Resolve(ctx context.Context, q Querier, name string) (int64, error) {
+	name = Normalize(name)
+	if name == "" {
+		return 0, ErrNameRequired
+	}
+
+	var id int64
+	err := q.QueryRow(ctx, "SELECT id FROM tags WHERE LOWER(name) = LOWER($1)", name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to find tag: %w", err)
+	}
+
+	tagSlug, err := uniqueSlug(ctx, q, name, 0)
+	if err != nil {
+		return 0, err
+	}
+	err = q.QueryRow(ctx, `INSERT INTO tags (name, slug) VALUES ($1, $2) ON CONFLICT ((LOWER(name))) DO UPDATE SET name = tags.name RETURNING id`, name, tagSlug).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create tag: %w", err)
+	}
+	return id, nil
+}
+
+// uniqueSlug returns the slug of name, adding a numeric suffix if another tag already has it
+func uniqueSlug(ctx context.Context, q Querier, name string, excludeID int64) (string, error) {
+	base := Slug(name)
+	candidate := base
+	for n := 2; ; n++ {
+		var taken bool
+		err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM tags WHERE slug = $1 AND id <> $2)", candidate, excludeID).Scan(&taken)
+		if err != nil {
+			return "", fmt.Errorf("failed to check tag slug: %w", err)
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// Service
+type Service struct {
+	db    *pgxpool.Pool
+	audit *audit.Service
+}
+
+func NewService(db *pgxpool.Pool, auditService *audit.Service) *Service {
+	return &Service{db: db, audit: auditService}
+}
+
+const tagColumns = `t.id, t.name, t.slug,
+	(SELECT COUNT(*) FROM project_tags pt WHERE pt.tag_id = t.id),
+	(SELECT COUNT(*) FROM event_tags et WHERE et.tag_id = t.id)`
+
+// unusedCondition matches tags that no project or event uses
+const unusedCondition = `NOT EXISTS (SELECT 1 FROM project_tags pt WHERE pt.tag_id = t.id)
+	AND NOT EXISTS (SELECT 1 FROM event_tags et WHERE et.tag_id = t.id)`
+
+func scanTag(row pgx.Row, t *Tag) error {
+	if err := row.Scan(&t.ID, &t.Name, &t.Slug, &t.ProjectsCount, &t.EventsCount); err != nil {
+		return err
+	}
+	t.UsageCount = t.ProjectsCount + t.EventsCount
+	return nil
+}
+
+// List returns all tags by name with their usage counts
+func (s *Service) List(ctx context.Context, params ListParams) ([]Tag, error) {
+	query := "SELECT " + tagColumns + " FROM tags t WHERE 1=1"
+	var args []any
+
+	if params.Search != "" {
+		args = append(args, "%"+params.Search+"%")
+		query += fmt.Sprintf(" AND t.name ILIKE $%d", len(args))
+	}
+	if params.Unused {
+		query += " AND " + unusedCondition
+	}
+
+	rows, err := s.db.Query(ctx, query+" ORDER BY LOWER(t.name)", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := scanTag(rows, &t); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (s *Service) GetByID(ctx context.Context, id int64) (*Tag, error) {
+	var t Tag
+	err := scanTag(s.db.QueryRow(ctx, "SELECT "+tagColumns+" FROM tags t WHERE t.id = $1", id), &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Rename changes the name of a tag and regenerates its slug. A name used by another tag
+// is rejected; such tags are combined with Merge.
+func (s *Service) Rename(ctx context.Context, id int64, req *RenameRequest, userID int64, ip string) (*Tag, error) {
+	name := Normalize(req.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if name == existing.Name {
+		return existing, nil
+	}
+
+	tagSlug, err := uniqueSlug(ctx, s.db, name, id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec(ctx, "UPDATE tags SET name = $1, slug = $2 WHERE id = $3", name, tagSlug, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+
+	t, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityTag, &id, map[string]any{"before": existing, "after": t}, ip)
+	return t, nil
+}
+
+// Merge relinks the projects and events of the source tags to the target tag and deletes the sources
+func (s *Service) Merge(ctx context.Context, req *MergeRequest, userID int64, ip string) (*Tag, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids := append([]int64{req.TargetID}, req.SourceIDs...)
+	rows, err := tx.Query(ctx, "SELECT "+tagColumns+" FROM tags t WHERE t.id = ANY($1) FOR UPDATE", ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	found := make(map[int64]Tag, len(ids))
+	for rows.Next() {
+		var t Tag
+		if err := scanTag(rows, &t); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		found[t.ID] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, strconv.FormatInt(id, 10))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTagNotFound, strings.Join(missing, ", "))
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO project_tags (project_id, tag_id)
+		SELECT project_id, $1 FROM project_tags WHERE tag_id = ANY($2)
+		ON CONFLICT DO NOTHING`, req.TargetID, req.SourceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to relink projects: %w", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO event_tags (event_id, tag_id)
+		SELECT event_id, $1 FROM event_tags WHERE tag_id = ANY($2)
+		ON CONFLICT DO NOTHING`, req.TargetID, req.SourceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to relink events: %w", err)
+	}
+	// Links of the sources are removed by ON DELETE CASCADE
+	if _, err := tx.Exec(ctx, "DELETE FROM tags WHERE id = ANY($1)", req.SourceIDs); err != nil {
+		return nil, fmt.Errorf("failed to delete merged tags: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	target, err := s.GetByID(ctx, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make([]Tag, 0, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		merged = append(merged, found[id])
+	}
+	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityTag, &target.ID, map[string]any{
+		"action": "merge",
+		"merged": merged,
+		"before": found[req.TargetID],
+		"after":  target,
+	}, ip)
+	return target, nil
+}
+
+// Delete removes a tag that no project or event uses
+func (s *Service) Delete(ctx context.Context, id int64, userID int64, ip string) error {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing.UsageCount > 0 {
+		return ErrTagInUse
+	}
+
+	// The condition guards against a project or event taking the tag meanwhile
+	tag, err := s.db.Exec(ctx, "DELETE FROM tags t WHERE t.id = $1 AND "+unusedCondition, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTagInUse
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionDelete, audit.EntityTag, &id, existing, ip)
+	return nil
+}
+
+// DeleteUnused removes all tags that no project or event uses
+func (s *Service) DeleteUnused(ctx context.Context, userID int64, ip string) (*DeleteUnusedResult, error) {
+	rows, err := s.db.Query(ctx, "DELETE FROM tags t WHERE "+unusedCondition+" RETURNING t.id, t.name, t.slug")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &DeleteUnusedResult{Tags: []Tag{}}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Slug); err != nil {
+			return nil, err
+		}
+		result.Tags = append(result.Tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.Deleted = len(result.Tags)
+
+	if result.Deleted > 0 {
+		s.audit.LogAction(ctx, &userID, audit.ActionDelete, audit.EntityTag, nil, map[string]any{"action": "delete_unused", "deleted": result.Tags}, ip)
+	}
+	return result, nil
+}
+
+// Handler
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func writeError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, ErrTagNotFound):
+		response.NotFound(w, err.Error())
+	case errors.Is(err, ErrNameRequired), errors.Is(err, ErrSourceRequired), errors.Is(err, ErrTargetRequired), errors.Is(err, ErrMergeIntoSelf):
+		response.ValidationError(w, err.Error())
+	case errors.Is(err, ErrTagExists):
+		response.Conflict(w, "tag with this name already exists, merge the tags instead")
+	case errors.Is(err, ErrTagInUse):
+		response.Conflict(w, "tag is used by projects or events, merge it into another tag instead")
+	default:
+		slog.Error("failed to "+action+" tags", "error", err)
+		response.InternalError(w, "failed to "+action+" tags")
+	}
+}
+
+// List handles GET /api/tags?search=&unused=true
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	params := ListParams{
+		Search: r.URL.Query().Get("search"),
+		Unused: r.URL.Query().Get("unused") == "true",
+	}
+
+	tags, err := h.service.List(r.Context(), params)
+	if err != nil {
+		writeError(w, err, "list")
+		return
+	}
+	response.JSON(w, http.StatusOK, tags)
+}
+
+// Rename handles PUT /api/tags/:id
+func (h *Handler) Rename(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid tag id")
+		return
+	}
+
+	var req RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	tag, err := h.service.Rename(r.Context(), id, &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeError(w, err, "rename")
+		return
+	}
+	response.JSON(w, http.StatusOK, tag)
+}
+
+// Merge handles POST /api/tags/merge
+func (h *Handler) Merge(w http.ResponseWriter, r *http.Request) {
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	tag, err := h.service.Merge(r.Context(), &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeError(w, err, "merge")
+		return
+	}
+	response.JSON(w, http.StatusOK, tag)
+}
+
+// Delete handles DELETE /api/tags/:id
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid tag id")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.service.Delete(r.Context(), id, userID, r.RemoteAddr); err != nil {
+		writeError(w, err, "delete")
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "tag deleted"})
+}
+
+// DeleteUnused handles DELETE /api/tags/unused
+func (h *Handler) DeleteUnused(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.DeleteUnused(r.Context(), userID, r.RemoteAddr)
+	if err != nil {
+		writeError(w, err, "delete")
+		return
+	}
+	response.JSON(w, http.StatusOK, result)
+}
@@ -15,6 +15,9 @@ type Member struct {
 	Name         string    `json:"name"`
 	Role         *string   `json:"role"`
 	Photo        *string   `json:"photo"`
+	PhotoAlt     *string   `json:"photo_alt,omitempty"`     // Public list only, from the media library
+	PhotoFocalX  *float64  `json:"photo_focal_x,omitempty"` // Focal point for smart cropping, 0..1
+	PhotoFocalY  *float64  `json:"photo_focal_y,omitempty"`
 	ClubID       *int64    `json:"club_id"`
 	ClubName     *string   `json:"club_name,omitempty"`
 	Badge        *string   `json:"badge"`
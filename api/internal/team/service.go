@@ -79,7 +79,7 @@ func (s *Service) List(ctx context.Context, params ListParams) (*ListResponse, e
 }
 
 func (s *Service) ListPublic(ctx context.Context) ([]Member, error) {
-	rows, err := s.db.Query(ctx, `SELECT tm.id, tm.name, tm.role, tm.photo, tm.club_id, c.name, tm.badge, tm.telegram_link, tm.sort_order, tm.is_visible, tm.created_at, tm.updated_at FROM team_members tm LEFT JOIN clubs c ON tm.club_id = c.id WHERE tm.is_visible = true ORDER BY tm.sort_order DESC, tm.created_at DESC`)
+	rows, err := s.db.Query(ctx, `SELECT tm.id, tm.name, tm.role, tm.photo, md.alt_text, md.focal_x, md.focal_y, tm.club_id, c.name, tm.badge, tm.telegram_link, tm.sort_order, tm.is_visible, tm.created_at, tm.updated_at FROM team_members tm LEFT JOIN clubs c ON tm.club_id = c.id LEFT JOIN media md ON md.url = tm.photo WHERE tm.is_visible = true ORDER BY tm.sort_order DESC, tm.created_at DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -88,7 +88,7 @@ func (s *Service) ListPublic(ctx context.Context) ([]Member, error) {
 	var members []Member
 	for rows.Next() {
 		var m Member
-		if err := rows.Scan(&m.ID, &m.Name, &m.Role, &m.Photo, &m.ClubID, &m.ClubName, &m.Badge, &m.TelegramLink, &m.SortOrder, &m.IsVisible, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.Role, &m.Photo, &m.PhotoAlt, &m.PhotoFocalX, &m.PhotoFocalY, &m.ClubID, &m.ClubName, &m.Badge, &m.TelegramLink, &m.SortOrder, &m.IsVisible, &m.CreatedAt, &m.UpdatedAt); err != nil {
 			return nil, err
 		}
 		members = append(members, m)
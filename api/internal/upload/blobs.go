@@ -0,0 +1,114 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Blob kinds
+const (
+	blobKindImage = "image"
+	blobKindSVG   = "svg"
+	blobKindVideo = "video"
+)
+
+// blob is a content-addressed upload tracked in media_blobs
+type blob struct {
+	Hash     string
+	Kind     string
+	URL      string
+	Size     int64
+	Result   json.RawMessage
+	RefCount int
+}
+
+// contentHash returns the hex SHA-256 of the content, used as the stored filename
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// acquireBlob adds a reference to already stored content. Returns nil when the content is new.
+func (s *Service) acquireBlob(ctx context.Context, hash string) (*blob, error) {
+	var b blob
+	err := s.db.QueryRow(ctx, `UPDATE media_blobs SET ref_count = ref_count + 1 WHERE hash = $1 RETURNING hash, kind, url, size, result, ref_count`, hash).Scan(
+		&b.Hash, &b.Kind, &b.URL, &b.Size, &b.Result, &b.RefCount,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire blob: %w", err)
+	}
+	return &b, nil
+}
+
+// registerBlob records newly stored content with a single reference.
+// A concurrent upload of the same content ends up as a second reference.
+func (s *Service) registerBlob(ctx context.Context, b *blob) error {
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO media_blobs (hash, kind, url, size, result)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (hash) DO UPDATE SET ref_count = media_blobs.ref_count + 1
+		RETURNING ref_count
+	`, b.Hash, b.Kind, b.URL, b.Size, b.Result).Scan(&b.RefCount)
+	if err != nil {
+		return fmt.Errorf("failed to register blob: %w", err)
+	}
+	return nil
+}
+
+// releaseBlob drops one reference to the content stored at url. With the last
+// reference the row is deleted and remove is called while the row is still locked,
+// so a concurrent upload of the same content never gets a URL whose files are vanishing.
+// The last reference is kept (ErrMediaInUse) while an entity still points at the file.
+// Returns false when the URL is not tracked (files uploaded before deduplication).
+func (s *Service) releaseBlob(ctx context.Context, url string, remove func() error) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var refCount int
+	err = tx.QueryRow(ctx, "SELECT ref_count FROM media_blobs WHERE url = $1 FOR UPDATE", url).Scan(&refCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock blob: %w", err)
+	}
+
+	if refCount > 1 {
+		if _, err := tx.Exec(ctx, "UPDATE media_blobs SET ref_count = ref_count - 1 WHERE url = $1", url); err != nil {
+			return true, fmt.Errorf("failed to release blob: %w", err)
+		}
+		return true, tx.Commit(ctx)
+	}
+
+	inUse, err := isReferenced(ctx, tx, fileKey(url))
+	if err != nil {
+		return true, err
+	}
+	if inUse {
+		return true, ErrMediaInUse
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM media_blobs WHERE url = $1", url); err != nil {
+		return true, fmt.Errorf("failed to delete blob: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM media WHERE url = $1", url); err != nil {
+		return true, fmt.Errorf("failed to delete media: %w", err)
+	}
+	if err := remove(); err != nil {
+		return true, err
+	}
+
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,211 @@
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "image/gif" // Register GIF decoder
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register WebP decoder
+)
+
+// Responsive widths generated for every uploaded image (only those narrower than the original)
+var responsiveWidths = []int{320, 640, 960, 1280}
+
+const (
+	// maxImageWidth caps the width of the main rendition
+	maxImageWidth = 1920
+	jpegQuality   = 82
+)
+
+// Output formats
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatGIF  = "gif"
+)
+
+var formatExtensions = map[string]string{
+	FormatJPEG: ".jpg",
+	FormatPNG:  ".png",
+	FormatWebP: ".webp",
+	FormatGIF:  ".gif",
+}
+
+var formatMIMETypes = map[string]string{
+	FormatJPEG: "image/jpeg",
+	FormatPNG:  "image/png",
+	FormatWebP: "image/webp",
+	FormatGIF:  "image/gif",
+}
+
+// ImageVariant is a single rendition of an uploaded image
+type ImageVariant struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"`
+	Size   int64  `json:"size"`
+}
+
+// ImageResult is the result of an image upload
+type ImageResult struct {
+	URL      string            `json:"url"`
+	Width    int               `json:"width"`
+	Height   int               `json:"height"`
+	Variants []ImageVariant    `json:"variants"`
+	Srcset   map[string]string `json:"srcset"` // MIME type -> srcset attribute value
+}
+
+// rendition is an encoded image waiting to be written to storage
+type rendition struct {
+	suffix string // Appended to the base filename, e.g. "-640w"
+	format string
+	width  int
+	height int
+	data   []byte
+}
+
+// processImage decodes an image, applies its EXIF orientation and re-encodes it
+// into the main rendition plus responsive widths. Re-encoding drops all metadata.
+//
+// Opaque images are written as JPEG, images with transparency as PNG. The pure-Go
+// WebP encoder is lossless, which only pays off for flat graphics: WebP renditions
+// are produced for PNG output, and kept when smaller than the PNG. Photographs
+// (JPEG output) get no WebP variant, since a lossless encode is larger than the JPEG.
+// There is no AVIF rendition: no AVIF encoder is available without cgo.
+func processImage(data []byte) ([]rendition, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFileType, err)
+	}
+
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	return renderImage(img)
+}
+
+// renderImage encodes a decoded image into the main rendition plus responsive widths
+func renderImage(img image.Image) ([]rendition, error) {
+	outFormat := FormatJPEG
+	if hasAlpha(img) {
+		outFormat = FormatPNG
+	}
+
+	// Main rendition, downscaled to maxImageWidth
+	main := img
+	if main.Bounds().Dx() > maxImageWidth {
+		main = resizeToWidth(img, maxImageWidth)
+	}
+
+	var renditions []rendition
+	add := func(suffix string, src image.Image) error {
+		encoded, err := encodeImage(src, outFormat)
+		if err != nil {
+			return err
+		}
+		b := src.Bounds()
+		renditions = append(renditions, rendition{suffix: suffix, format: outFormat, width: b.Dx(), height: b.Dy(), data: encoded})
+
+		if outFormat != FormatPNG {
+			return nil
+		}
+		webp, err := encodeImage(src, FormatWebP)
+		if err == nil && len(webp) < len(encoded) {
+			renditions = append(renditions, rendition{suffix: suffix, format: FormatWebP, width: b.Dx(), height: b.Dy(), data: webp})
+		}
+		return nil
+	}
+
+	if err := add("", main); err != nil {
+		return nil, err
+	}
+
+	// Resize widest first, each step from the previous one, to keep large photos cheap
+	widths := append([]int(nil), responsiveWidths...)
+	sort.Sort(sort.Reverse(sort.IntSlice(widths)))
+	src := main
+	for _, width := range widths {
+		if width >= main.Bounds().Dx() {
+			continue
+		}
+		src = resizeToWidth(src, width)
+		if err := add("-"+strconv.Itoa(width)+"w", src); err != nil {
+			return nil, err
+		}
+	}
+
+	return renditions, nil
+}
+
+// resizeToWidth scales an image to the given width, keeping the aspect ratio
+func resizeToWidth(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// hasAlpha reports whether the image has any transparent pixels
+func hasAlpha(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	return true
+}
+
+// encodeImage encodes an image in the given output format
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case FormatJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case FormatPNG:
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		err = enc.Encode(&buf, img)
+	case FormatWebP:
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = fmt.Errorf("unsupported output format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// buildSrcset groups variants by MIME type into srcset attribute values
+func buildSrcset(variants []ImageVariant) map[string]string {
+	byType := map[string][]ImageVariant{}
+	for _, v := range variants {
+		mime := formatMIMETypes[v.Format]
+		byType[mime] = append(byType[mime], v)
+	}
+
+	srcset := make(map[string]string, len(byType))
+	for mime, list := range byType {
+		sort.Slice(list, func(i, j int) bool { return list[i].Width < list[j].Width })
+		parts := make([]string, 0, len(list))
+		for _, v := range list {
+			parts = append(parts, v.URL+" "+strconv.Itoa(v.Width)+"w")
+		}
+		srcset[mime] = strings.Join(parts, ", ")
+	}
+	return srcset
+}
@@ -0,0 +1,530 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/itam-misis/itam-api/internal/audit"
+)
+
+var (
+	ErrMediaNotFound = errors.New("media not found")
+	ErrMediaInUse    = errors.New("media is still in use")
+
+	// fileKeyPattern extracts the filename stem shared by an upload and its renditions.
+	// Must match the expression used by the media_references view.
+	fileKeyPattern = regexp.MustCompile(`/([0-9a-f]{32,64})[^/]*$`)
+)
+
+// orphanGracePeriod keeps fresh uploads that are not attached to an entity yet
+const orphanGracePeriod = 24 * time.Hour
+
+// storedPrefixes are the storage key prefixes holding uploads
+var storedPrefixes = []string{"images/", "svg/", "videos/", incomingPrefix}
+
+// orphanPrefixes are scanned for orphans. Videos are left out: media_references only finds them
+// embedded in HTML content, not pasted into other fields, so they are only removed by hand.
+var orphanPrefixes = []string{"images/", "svg/", incomingPrefix}
+
+// Media is an upload in the media library
+type Media struct {
+	ID          int64        `json:"id"`
+	URL         string       `json:"url"`
+	Kind        string       `json:"kind"`
+	MIME        string       `json:"mime"`
+	Size        int64        `json:"size"`
+	Width       *int         `json:"width"`
+	Height      *int         `json:"height"`
+	AltText     *string      `json:"alt_text"`
+	FocalPoint  *FocalPoint  `json:"focal_point"`
+	DerivedFrom *int64       `json:"derived_from"`
+	UploadedBy  *int64       `json:"uploaded_by"`
+	UsageCount  int          `json:"usage_count"`
+	Usages      []MediaUsage `json:"usages,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// FocalPoint is the point of interest of an image relative to its size, (0.5, 0.5) being the center
+type FocalPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func (p *FocalPoint) valid() bool {
+	return p.X >= 0 && p.X <= 1 && p.Y >= 0 && p.Y <= 1
+}
+
+// MediaUsage is an entity field that references an upload
+type MediaUsage struct {
+	EntityType string `json:"entity_type"`
+	EntityID   int64  `json:"entity_id"`
+	Field      string `json:"field"`
+	URL        string `json:"url"`
+}
+
+// MediaListParams contains parameters for listing media
+type MediaListParams struct {
+	Page     int
+	PageSize int
+	Search   string
+	Kind     string
+	Unused   *bool
+}
+
+// MediaListResponse is the response for listing media
+type MediaListResponse struct {
+	Items      []Media `json:"items"`
+	Total      int     `json:"total"`
+	Page       int     `json:"page"`
+	PageSize   int     `json:"page_size"`
+	TotalPages int     `json:"total_pages"`
+}
+
+// UpdateMediaRequest is the request body for updating media metadata
+type UpdateMediaRequest struct {
+	AltText    *string     `json:"alt_text,omitempty"`
+	FocalPoint *FocalPoint `json:"focal_point,omitempty"`
+}
+
+// Orphan is a stored upload that no entity references
+type Orphan struct {
+	Key        string    `json:"key"`
+	URLs       []string  `json:"urls"`
+	Size       int64     `json:"size"`
+	InLibrary  bool      `json:"in_library"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// OrphanReport lists unreferenced uploads
+type OrphanReport struct {
+	Orphans   []Orphan `json:"orphans"`
+	Count     int      `json:"count"`
+	TotalSize int64    `json:"total_size"`
+	Deleted   bool     `json:"deleted"`
+}
+
+// fileKey returns the filename stem of an upload URL, or "" for URLs not produced by uploads
+func fileKey(url string) string {
+	m := fileKeyPattern.FindStringSubmatch(url)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// uploader returns the uploader reference for a media row; 0 means unknown
+func uploader(userID int64) *int64 {
+	if userID == 0 {
+		return nil
+	}
+	return &userID
+}
+
+const mediaColumns = `m.id, m.url, m.kind, m.mime, m.size, m.width, m.height, m.alt_text, m.focal_x, m.focal_y, m.derived_from, m.uploaded_by,
+	(SELECT COUNT(*) FROM media_references r WHERE r.file_key = m.file_key), m.created_at, m.updated_at`
+
+func scanMedia(row pgx.Row, m *Media) error {
+	var focalX, focalY *float64
+	err := row.Scan(&m.ID, &m.URL, &m.Kind, &m.MIME, &m.Size, &m.Width, &m.Height, &m.AltText, &focalX, &focalY, &m.DerivedFrom, &m.UploadedBy, &m.UsageCount, &m.CreatedAt, &m.UpdatedAt)
+	if err == nil && focalX != nil && focalY != nil {
+		m.FocalPoint = &FocalPoint{X: *focalX, Y: *focalY}
+	}
+	return err
+}
+
+// recordMedia adds an upload to the media library. Re-uploads of the same content keep the first row.
+func (s *Service) recordMedia(ctx context.Context, m *Media) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO media (url, file_key, kind, mime, size, width, height, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (url) DO NOTHING
+	`, m.URL, fileKey(m.URL), m.Kind, m.MIME, m.Size, m.Width, m.Height, m.UploadedBy)
+	if err != nil {
+		return fmt.Errorf("failed to record media: %w", err)
+	}
+	return nil
+}
+
+// ListMedia returns a paginated list of uploads with their usage counts
+func (s *Service) ListMedia(ctx context.Context, params MediaListParams) (*MediaListResponse, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 20
+	}
+
+	offset := (params.Page - 1) * params.PageSize
+	baseQuery := "FROM media m WHERE 1=1"
+	var args []any
+	argNum := 1
+
+	if params.Search != "" {
+		baseQuery += fmt.Sprintf(" AND (m.url ILIKE $%d OR m.alt_text ILIKE $%d)", argNum, argNum)
+		args = append(args, "%"+params.Search+"%")
+		argNum++
+	}
+	if params.Kind != "" {
+		baseQuery += fmt.Sprintf(" AND m.kind = $%d", argNum)
+		args = append(args, params.Kind)
+		argNum++
+	}
+	if params.Unused != nil {
+		exists := "EXISTS (SELECT 1 FROM media_references r WHERE r.file_key = m.file_key)"
+		if *params.Unused {
+			baseQuery += " AND NOT " + exists
+		} else {
+			baseQuery += " AND " + exists
+		}
+	}
+
+	var total int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s %s ORDER BY m.created_at DESC LIMIT $%d OFFSET $%d", mediaColumns, baseQuery, argNum, argNum+1)
+	args = append(args, params.PageSize, offset)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Media{}
+	for rows.Next() {
+		var m Media
+		if err := scanMedia(rows, &m); err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &MediaListResponse{
+		Items:      items,
+		Total:      total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: (total + params.PageSize - 1) / params.PageSize,
+	}, nil
+}
+
+// GetMedia returns an upload with every entity that references it
+func (s *Service) GetMedia(ctx context.Context, id int64) (*Media, error) {
+	var m Media
+	err := scanMedia(s.db.QueryRow(ctx, "SELECT "+mediaColumns+" FROM media m WHERE m.id = $1", id), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.Usages, err = s.Usages(ctx, m.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Usages returns the entity fields referencing the upload at url or any of its renditions
+func (s *Service) Usages(ctx context.Context, url string) ([]MediaUsage, error) {
+	usages := []MediaUsage{}
+	key := fileKey(url)
+	if key == "" {
+		return usages, nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT entity_type, entity_id, field, url FROM media_references
+		WHERE file_key = $1 ORDER BY entity_type, entity_id, field
+	`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u MediaUsage
+		if err := rows.Scan(&u.EntityType, &u.EntityID, &u.Field, &u.URL); err != nil {
+			return nil, err
+		}
+		usages = append(usages, u)
+	}
+	return usages, rows.Err()
+}
+
+// isReferenced reports whether any entity references the file key
+func isReferenced(ctx context.Context, q interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	var exists bool
+	err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM media_references WHERE file_key = $1)", key).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check media usage: %w", err)
+	}
+	return exists, nil
+}
+
+// UpdateMedia updates the metadata of an upload
+func (s *Service) UpdateMedia(ctx context.Context, id int64, req *UpdateMediaRequest, userID int64, ip string) (*Media, error) {
+	if req.FocalPoint != nil {
+		if !req.FocalPoint.valid() {
+			return nil, ErrInvalidFocalPoint
+		}
+		if _, err := s.db.Exec(ctx, "UPDATE media SET focal_x = $1, focal_y = $2 WHERE id = $3", req.FocalPoint.X, req.FocalPoint.Y, id); err != nil {
+			return nil, err
+		}
+	}
+	if req.AltText != nil {
+		altText := strings.TrimSpace(*req.AltText)
+		var err error
+		if altText == "" {
+			_, err = s.db.Exec(ctx, "UPDATE media SET alt_text = NULL WHERE id = $1", id)
+		} else {
+			_, err = s.db.Exec(ctx, "UPDATE media SET alt_text = $1 WHERE id = $2", altText, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := s.GetMedia(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityMedia, &id, req, ip)
+
+	return m, nil
+}
+
+// DeleteMedia removes an upload and all its renditions regardless of how many times it was uploaded.
+// Uploads still referenced by an entity are refused with ErrMediaInUse.
+func (s *Service) DeleteMedia(ctx context.Context, id int64, userID int64, ip string) error {
+	var url string
+	err := s.db.QueryRow(ctx, "SELECT url FROM media WHERE id = $1", id).Scan(&url)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrMediaNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.purgeKey(ctx, fileKey(url), nil); err != nil {
+		return err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionDelete, audit.EntityMedia, &id, map[string]string{"url": url}, ip)
+
+	return nil
+}
+
+// purgeKey deletes every stored file, blob and library row for a file key inside one transaction,
+// re-checking usage while the blob row is locked. keys may be nil to look them up in storage.
+func (s *Service) purgeKey(ctx context.Context, key string, keys []string) error {
+	if key == "" {
+		return ErrMediaNotFound
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM media_blobs WHERE hash = $1 FOR UPDATE", key); err != nil {
+		return fmt.Errorf("failed to lock blob: %w", err)
+	}
+
+	inUse, err := isReferenced(ctx, tx, key)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrMediaInUse
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM media_blobs WHERE hash = $1", key); err != nil {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM media WHERE file_key = $1", key); err != nil {
+		return fmt.Errorf("failed to delete media: %w", err)
+	}
+
+	if keys == nil {
+		for _, prefix := range storedPrefixes {
+			found, err := s.storedKeys(ctx, prefix+key)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, found...)
+		}
+	}
+	for _, k := range keys {
+		if err := s.storage.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// FindOrphans lists stored uploads that no entity references and that are older than the grace period.
+// Files uploaded before the media library existed are included as well.
+func (s *Service) FindOrphans(ctx context.Context) (*OrphanReport, error) {
+	groups := map[string]*Orphan{}
+	for _, prefix := range orphanPrefixes {
+		objects, err := s.storage.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			url := s.url(o.Key)
+			key := fileKey(url)
+			if key == "" {
+				continue
+			}
+
+			g, ok := groups[key]
+			if !ok {
+				g = &Orphan{Key: key}
+				groups[key] = g
+			}
+			g.URLs = append(g.URLs, url)
+			g.Size += o.Size
+			if o.ModifiedAt.After(g.ModifiedAt) {
+				g.ModifiedAt = o.ModifiedAt
+			}
+		}
+	}
+
+	referenced, err := s.keySet(ctx, "SELECT DISTINCT file_key FROM media_references WHERE file_key IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	inLibrary, err := s.keySet(ctx, "SELECT file_key FROM media")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OrphanReport{Orphans: []Orphan{}}
+	cutoff := time.Now().Add(-orphanGracePeriod)
+	for key, g := range groups {
+		if referenced[key] || g.ModifiedAt.After(cutoff) {
+			continue
+		}
+		g.InLibrary = inLibrary[key]
+		sort.Strings(g.URLs)
+		report.Orphans = append(report.Orphans, *g)
+		report.TotalSize += g.Size
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool {
+		return report.Orphans[i].ModifiedAt.Before(report.Orphans[j].ModifiedAt)
+	})
+	report.Count = len(report.Orphans)
+
+	return report, nil
+}
+
+func (s *Service) keySet(ctx context.Context, query string) (map[string]bool, error) {
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		set[key] = true
+	}
+	return set, rows.Err()
+}
+
+// CleanupOrphans deletes every orphaned upload and records a single audit entry.
+// userID is nil when run by the background job.
+func (s *Service) CleanupOrphans(ctx context.Context, userID *int64, ip string) (*OrphanReport, error) {
+	report, err := s.FindOrphans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]Orphan, 0, len(report.Orphans))
+	var size int64
+	for _, o := range report.Orphans {
+		keys := make([]string, 0, len(o.URLs))
+		for _, url := range o.URLs {
+			if k, err := s.keyFromURL(url); err == nil {
+				keys = append(keys, k)
+			}
+		}
+		if err := s.purgeKey(ctx, o.Key, keys); err != nil {
+			if errors.Is(err, ErrMediaInUse) {
+				continue // Referenced since the scan
+			}
+			return nil, err
+		}
+		deleted = append(deleted, o)
+		size += o.Size
+	}
+
+	if len(deleted) > 0 {
+		s.audit.LogAction(ctx, userID, audit.ActionDelete, audit.EntityMedia, nil, map[string]any{
+			"action": "cleanup_orphans",
+			"count":  len(deleted),
+			"size":   size,
+		}, ip)
+	}
+
+	return &OrphanReport{Orphans: deleted, Count: len(deleted), TotalSize: size, Deleted: true}, nil
+}
+
+// RunOrphanJob reports (or deletes, when remove is set) orphaned uploads every interval until ctx is done
+func (s *Service) RunOrphanJob(ctx context.Context, interval time.Duration, remove bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var report *OrphanReport
+		var err error
+		if remove {
+			report, err = s.CleanupOrphans(ctx, nil, "")
+		} else {
+			report, err = s.FindOrphans(ctx)
+		}
+		if err != nil {
+			slog.Error("orphan upload job failed", "error", err)
+			continue
+		}
+		if report.Count > 0 {
+			slog.Info("orphan uploads", "count", report.Count, "size", report.TotalSize, "deleted", report.Deleted)
+		}
+	}
+}
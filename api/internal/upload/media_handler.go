@@ -0,0 +1,152 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// ListMedia handles GET /api/media
+func (h *Handler) ListMedia(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	params := MediaListParams{
+		Search:   q.Get("search"),
+		Kind:     q.Get("kind"),
+		Page:     1,
+		PageSize: 20,
+	}
+	if p, _ := strconv.Atoi(q.Get("page")); p > 0 {
+		params.Page = p
+	}
+	if ps, _ := strconv.Atoi(q.Get("page_size")); ps > 0 {
+		params.PageSize = ps
+	}
+	if unused := q.Get("unused"); unused != "" {
+		v := unused == "true"
+		params.Unused = &v
+	}
+
+	result, err := h.service.ListMedia(r.Context(), params)
+	if err != nil {
+		slog.Error("failed to list media", "error", err)
+		response.InternalError(w, "failed to list media")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// GetMedia handles GET /api/media/:id
+func (h *Handler) GetMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid media ID")
+		return
+	}
+
+	media, err := h.service.GetMedia(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrMediaNotFound) {
+			response.NotFound(w, "media not found")
+			return
+		}
+		slog.Error("failed to get media", "error", err)
+		response.InternalError(w, "failed to get media")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, media)
+}
+
+// UpdateMedia handles PUT /api/media/:id
+func (h *Handler) UpdateMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid media ID")
+		return
+	}
+
+	var req UpdateMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	media, err := h.service.UpdateMedia(r.Context(), id, &req, userID, r.RemoteAddr)
+	if err != nil {
+		if errors.Is(err, ErrMediaNotFound) {
+			response.NotFound(w, "media not found")
+			return
+		}
+		if errors.Is(err, ErrInvalidFocalPoint) {
+			response.ValidationError(w, "focal_point coordinates must be between 0 and 1")
+			return
+		}
+		slog.Error("failed to update media", "error", err)
+		response.InternalError(w, "failed to update media")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, media)
+}
+
+// DeleteMedia handles DELETE /api/media/:id
+func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid media ID")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.service.DeleteMedia(r.Context(), id, userID, r.RemoteAddr); err != nil {
+		switch {
+		case errors.Is(err, ErrMediaNotFound):
+			response.NotFound(w, "media not found")
+		case errors.Is(err, ErrMediaInUse):
+			response.Conflict(w, "media is still used, remove it from all entities first")
+		default:
+			slog.Error("failed to delete media", "error", err)
+			response.InternalError(w, "failed to delete media")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "media deleted"})
+}
+
+// Orphans handles GET /api/media/orphans
+func (h *Handler) Orphans(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.FindOrphans(r.Context())
+	if err != nil {
+		slog.Error("failed to find orphaned uploads", "error", err)
+		response.InternalError(w, "failed to find orphaned uploads")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report)
+}
+
+// CleanupOrphans handles POST /api/media/orphans/cleanup
+func (h *Handler) CleanupOrphans(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	report, err := h.service.CleanupOrphans(r.Context(), &userID, r.RemoteAddr)
+	if err != nil {
+		slog.Error("failed to clean up orphaned uploads", "error", err)
+		response.InternalError(w, "failed to clean up orphaned uploads")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report)
+}
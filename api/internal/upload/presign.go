@@ -0,0 +1,218 @@
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+	"github.com/itam-misis/itam-api/internal/storage"
+)
+
+var (
+	ErrInvalidUploadKey = errors.New("invalid upload key")
+	ErrInvalidSize      = errors.New("size must be positive")
+)
+
+const (
+	// incomingPrefix holds files uploaded directly to storage until they are processed
+	incomingPrefix = "incoming/"
+	presignExpiry  = 15 * time.Minute
+)
+
+// PresignRequest asks for a direct upload URL
+type PresignRequest struct {
+	Kind        string `json:"kind"` // image or svg
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"` // Exact size in bytes; the upload must send this Content-Length
+}
+
+// PresignResult tells the client where to PUT the file
+type PresignResult struct {
+	UploadURL string    `json:"upload_url"`
+	Method    string    `json:"method"`
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CompleteRequest finishes a direct upload
+type CompleteRequest struct {
+	Key         string `json:"key"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"content_type"`
+}
+
+// PresignUpload returns a URL the admin app can upload a file to without passing it through the API.
+// The file lands under incoming/ and is only published after CompleteUpload processes it.
+func (s *Service) PresignUpload(ctx context.Context, req *PresignRequest, userID int64) (*PresignResult, error) {
+	if req.Size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	switch req.Kind {
+	case blobKindImage:
+		if _, ok := declaredFormats[req.ContentType]; !ok {
+			return nil, ErrInvalidFileType
+		}
+		if req.Size > s.config.MaxSize {
+			return nil, ErrFileTooLarge
+		}
+	case blobKindSVG:
+		if req.Size > maxSVGSize {
+			return nil, ErrFileTooLarge
+		}
+		req.ContentType = "image/svg+xml"
+	default:
+		return nil, ErrInvalidFileType
+	}
+	if err := s.checkQuota(ctx, userID, req.Size); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	key := incomingPrefix + hex.EncodeToString(id)
+
+	uploadURL, err := s.storage.PresignPut(ctx, key, presignExpiry, req.Size, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PresignResult{
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		Key:       key,
+		ExpiresAt: time.Now().Add(presignExpiry).UTC(),
+	}, nil
+}
+
+// CompleteUpload processes a file uploaded through a presigned URL exactly like a regular upload
+// and removes the incoming copy, also when processing fails. Returns an *ImageResult for images and the URL for SVGs.
+func (s *Service) CompleteUpload(ctx context.Context, req *CompleteRequest, userID int64) (any, error) {
+	id, ok := strings.CutPrefix(req.Key, incomingPrefix)
+	if !ok || len(id) != 32 || strings.Trim(id, "0123456789abcdef") != "" {
+		return nil, ErrInvalidUploadKey
+	}
+
+	// A rejected file cannot be completed again, so the incoming copy is never kept
+	defer func() {
+		if err := s.storage.Delete(context.WithoutCancel(ctx), req.Key); err != nil {
+			slog.Warn("failed to delete incoming upload", "key", req.Key, "error", err)
+		}
+	}()
+
+	obj, err := s.storage.Stat(ctx, req.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+
+	file, err := s.storage.Get(ctx, req.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	defer file.Close()
+
+	var result any
+	switch req.Kind {
+	case blobKindImage:
+		result, err = s.UploadImage(ctx, file, req.ContentType, obj.Size, userID)
+	case blobKindSVG:
+		var url string
+		url, err = s.UploadSVG(ctx, io.LimitReader(file, maxSVGSize+1), obj.Size, userID)
+		result = map[string]string{"url": url}
+	default:
+		err = ErrInvalidFileType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Presign handles POST /api/upload/presign
+func (h *Handler) Presign(w http.ResponseWriter, r *http.Request) {
+	var req PresignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.PresignUpload(r.Context(), &req, userID)
+	if quotaError(w, err) {
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrPresignUnsupported):
+			response.BadRequest(w, "direct uploads are not available, use /api/upload/image or /api/upload/svg")
+		case errors.Is(err, ErrInvalidFileType):
+			response.ValidationError(w, "kind must be image or svg with an allowed content_type")
+		case errors.Is(err, ErrFileTooLarge):
+			response.ValidationError(w, "file too large")
+		case errors.Is(err, ErrInvalidSize):
+			response.ValidationError(w, "size must be the exact file size in bytes")
+		default:
+			slog.Error("failed to presign upload", "error", err)
+			response.InternalError(w, "failed to presign upload")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// Complete handles POST /api/upload/complete
+func (h *Handler) Complete(w http.ResponseWriter, r *http.Request) {
+	var req CompleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.CompleteUpload(r.Context(), &req, userID)
+	if quotaError(w, err) {
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidUploadKey):
+			response.ValidationError(w, "invalid upload key")
+		case errors.Is(err, ErrFileNotFound):
+			response.NotFound(w, "uploaded file not found")
+		case errors.Is(err, ErrInvalidFileType):
+			response.BadRequest(w, "invalid file type")
+		case errors.Is(err, ErrFileTooLarge):
+			response.BadRequest(w, "file too large")
+		case errors.Is(err, ErrContentMismatch):
+			response.BadRequest(w, "file content does not match its type")
+		case errors.Is(err, ErrPolyglotDetected):
+			response.BadRequest(w, "file contains data that is not part of the image")
+		case errors.Is(err, ErrImageTooLarge):
+			response.BadRequest(w, "image dimensions too large")
+		default:
+			slog.Error("failed to complete upload", "error", err)
+			response.InternalError(w, "failed to complete upload")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,239 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrQuotaExceeded = errors.New("upload quota exceeded")
+
+// Quota scopes
+const (
+	QuotaScopeUser  = "user"
+	QuotaScopeTotal = "total"
+)
+
+// QuotaError reports which quota an upload would exceed
+type QuotaError struct {
+	Scope     string
+	Used      int64
+	Limit     int64
+	Requested int64
+}
+
+func (e *QuotaError) Error() string {
+	if e.Scope == QuotaScopeTotal {
+		return fmt.Sprintf("upload storage is full: %s of %s used, the file needs %s",
+			formatBytes(e.Used), formatBytes(e.Limit), formatBytes(e.Requested))
+	}
+	return fmt.Sprintf("your upload quota is exceeded: %s of %s used, the file needs %s",
+		formatBytes(e.Used), formatBytes(e.Limit), formatBytes(e.Requested))
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// formatBytes renders a size for error messages, e.g. "12.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Stored bytes are counted per media row: every stored file (with its renditions) belongs to
+// the user who uploaded it first, so uploading content that is already stored is free.
+const storedBytes = `COALESCE(b.size, m.size)`
+
+// UsageStat is the number of stored files and their size
+type UsageStat struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// KindUsage is the storage used by one kind of upload
+type KindUsage struct {
+	Kind string `json:"kind"`
+	UsageStat
+}
+
+// UploaderUsage is the storage used by one user; UserID is nil for uploads of deleted users
+type UploaderUsage struct {
+	UserID *int64  `json:"user_id"`
+	Name   *string `json:"name"`
+	Email  *string `json:"email"`
+	Role   *string `json:"role"`
+	Quota  int64   `json:"quota"` // 0 means unlimited
+	UsageStat
+}
+
+// StorageUsage is the admin report of stored uploads
+type StorageUsage struct {
+	Total      UsageStat       `json:"total"`
+	TotalQuota int64           `json:"total_quota"`
+	ByKind     []KindUsage     `json:"by_kind"`
+	ByUploader []UploaderUsage `json:"by_uploader"`
+}
+
+// UserUsage is the storage used by the current user against their quota
+type UserUsage struct {
+	UsageStat
+	Quota int64 `json:"quota"` // 0 means unlimited
+}
+
+// checkQuota refuses an upload of size bytes that would exceed the user's role quota
+// or the total quota. Called only when new content is about to be stored.
+func (s *Service) checkQuota(ctx context.Context, userID int64, size int64) error {
+	if s.config.TotalQuota > 0 {
+		var used int64
+		err := s.db.QueryRow(ctx, "SELECT COALESCE(SUM("+storedBytes+"), 0) FROM media m LEFT JOIN media_blobs b ON b.url = m.url").Scan(&used)
+		if err != nil {
+			return fmt.Errorf("failed to check upload quota: %w", err)
+		}
+		if used+size > s.config.TotalQuota {
+			return &QuotaError{Scope: QuotaScopeTotal, Used: used, Limit: s.config.TotalQuota, Requested: size}
+		}
+	}
+
+	if userID == 0 {
+		return nil
+	}
+
+	usage, err := s.UserUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usage.Quota > 0 && usage.Bytes+size > usage.Quota {
+		return &QuotaError{Scope: QuotaScopeUser, Used: usage.Bytes, Limit: usage.Quota, Requested: size}
+	}
+
+	return nil
+}
+
+// UserUsage returns the storage used by a user and the quota of their role
+func (s *Service) UserUsage(ctx context.Context, userID int64) (*UserUsage, error) {
+	var role string
+	var usage UserUsage
+	err := s.db.QueryRow(ctx, `
+		SELECT u.role, COUNT(m.id), COALESCE(SUM(`+storedBytes+`), 0)
+		FROM users u
+		LEFT JOIN media m ON m.uploaded_by = u.id
+		LEFT JOIN media_blobs b ON b.url = m.url
+		WHERE u.id = $1
+		GROUP BY u.role
+	`, userID).Scan(&role, &usage.Files, &usage.Bytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &usage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload usage: %w", err)
+	}
+	usage.Quota = s.config.Quotas[role]
+	return &usage, nil
+}
+
+// StorageUsage reports stored uploads by kind and by uploader, largest first
+func (s *Service) StorageUsage(ctx context.Context) (*StorageUsage, error) {
+	report := &StorageUsage{TotalQuota: s.config.TotalQuota, ByKind: []KindUsage{}, ByUploader: []UploaderUsage{}}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT m.kind, COUNT(*), COALESCE(SUM(`+storedBytes+`), 0)
+		FROM media m
+		LEFT JOIN media_blobs b ON b.url = m.url
+		GROUP BY m.kind
+		ORDER BY 3 DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k KindUsage
+		if err := rows.Scan(&k.Kind, &k.Files, &k.Bytes); err != nil {
+			return nil, err
+		}
+		report.Total.Files += k.Files
+		report.Total.Bytes += k.Bytes
+		report.ByKind = append(report.ByKind, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(ctx, `
+		SELECT m.uploaded_by, u.name, u.email, u.role, COUNT(*), COALESCE(SUM(`+storedBytes+`), 0)
+		FROM media m
+		LEFT JOIN media_blobs b ON b.url = m.url
+		LEFT JOIN users u ON u.id = m.uploaded_by
+		GROUP BY m.uploaded_by, u.name, u.email, u.role
+		ORDER BY 6 DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u UploaderUsage
+		if err := rows.Scan(&u.UserID, &u.Name, &u.Email, &u.Role, &u.Files, &u.Bytes); err != nil {
+			return nil, err
+		}
+		if u.Role != nil {
+			u.Quota = s.config.Quotas[*u.Role]
+		}
+		report.ByUploader = append(report.ByUploader, u)
+	}
+
+	return report, rows.Err()
+}
+
+// quotaError writes the response for a quota error and reports whether err was one
+func quotaError(w http.ResponseWriter, err error) bool {
+	var qe *QuotaError
+	if !errors.As(err, &qe) {
+		return false
+	}
+	response.Err(w, http.StatusRequestEntityTooLarge, response.ErrCodeQuotaExceeded, qe.Error())
+	return true
+}
+
+// Usage handles GET /api/upload/usage
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	usage, err := h.service.UserUsage(r.Context(), userID)
+	if err != nil {
+		slog.Error("failed to get upload usage", "error", err)
+		response.InternalError(w, "failed to get upload usage")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, usage)
+}
+
+// StorageUsage handles GET /api/media/usage
+func (h *Handler) StorageUsage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.StorageUsage(r.Context())
+	if err != nil {
+		slog.Error("failed to get storage usage", "error", err)
+		response.InternalError(w, "failed to get storage usage")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report)
+}
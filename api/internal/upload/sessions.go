@@ -0,0 +1,675 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSessionNotFound   = errors.New("upload session not found")
+	ErrSessionIncomplete = errors.New("upload session is missing chunks")
+	ErrChunkInvalid      = errors.New("invalid chunk index or size")
+	ErrChecksumMismatch  = errors.New("chunk checksum mismatch")
+	ErrSessionBusy       = errors.New("upload session is being assembled")
+
+	// videoTypes lists accepted video content types with their stored extension
+	videoTypes = map[string]string{
+		"video/webm": ".webm",
+		"video/mp4":  ".mp4",
+	}
+)
+
+const (
+	// DefaultChunkSize is used when the client does not ask for a chunk size
+	DefaultChunkSize = 5 << 20
+	minChunkSize     = 256 << 10
+	// maxChunkSize stays below the proxy request body limit (10 MB)
+	maxChunkSize = 8 << 20
+
+	sessionTTL = 24 * time.Hour
+	// assembleTimeout bounds assembling a complete session (up to 200 MB read twice from storage);
+	// it stays below the proxy read timeout (300 s)
+	assembleTimeout = 4 * time.Minute
+)
+
+// CreateSessionRequest starts a resumable upload
+type CreateSessionRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	ChunkSize   int    `json:"chunk_size,omitempty"`
+}
+
+// Session is the state of a resumable upload; Received lets a client resume after a disconnect
+type Session struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	ContentType string          `json:"content_type"`
+	Filename    *string         `json:"filename"`
+	Size        int64           `json:"size"`
+	ChunkSize   int             `json:"chunk_size"`
+	TotalChunks int             `json:"total_chunks"`
+	Received    []int           `json:"received"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	CompletedAt *time.Time      `json:"completed_at"`
+}
+
+// VideoResult is the result of a video upload
+type VideoResult struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// chunkKey is the staging key of a chunk; chunks of abandoned sessions are removed by CleanupSessions
+func chunkKey(sessionID string, index int) string {
+	return incomingPrefix + sessionID + "-" + strconv.Itoa(index)
+}
+
+// CreateSession validates the announced file and opens an upload session
+func (s *Service) CreateSession(ctx context.Context, req *CreateSessionRequest, userID int64) (*Session, error) {
+	kind := blobKindVideo
+	limit := s.config.MaxVideoSize
+	if _, ok := videoTypes[req.ContentType]; !ok {
+		if _, ok := declaredFormats[req.ContentType]; !ok {
+			return nil, ErrInvalidFileType
+		}
+		kind = blobKindImage
+		limit = s.config.MaxSize
+	}
+	if req.Size <= 0 {
+		return nil, ErrChunkInvalid
+	}
+	if req.Size > limit {
+		return nil, ErrFileTooLarge
+	}
+
+	chunkSize := req.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < minChunkSize || chunkSize > maxChunkSize {
+		return nil, ErrChunkInvalid
+	}
+	if err := s.checkQuota(ctx, userID, req.Size); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	var filename *string
+	if req.Filename != "" {
+		filename = &req.Filename
+	}
+
+	sess := &Session{
+		ID:          hex.EncodeToString(id),
+		Kind:        kind,
+		ContentType: req.ContentType,
+		Filename:    filename,
+		Size:        req.Size,
+		ChunkSize:   chunkSize,
+		TotalChunks: int((req.Size + int64(chunkSize) - 1) / int64(chunkSize)),
+		Received:    []int{},
+	}
+
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO upload_sessions (id, kind, content_type, filename, size, chunk_size, total_chunks, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW() + $9::interval)
+		RETURNING created_at, expires_at
+	`, sess.ID, sess.Kind, sess.ContentType, sess.Filename, sess.Size, sess.ChunkSize, sess.TotalChunks, uploader(userID), sessionTTL.String()).Scan(&sess.CreatedAt, &sess.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	return sess, nil
+}
+
+// GetSession returns a session of the user with the indexes of received chunks
+func (s *Service) GetSession(ctx context.Context, id string, userID int64) (*Session, error) {
+	var sess Session
+	err := s.db.QueryRow(ctx, `
+		SELECT id, kind, content_type, filename, size, chunk_size, total_chunks, result, created_at, expires_at, completed_at
+		FROM upload_sessions
+		WHERE id = $1 AND created_by IS NOT DISTINCT FROM $2 AND (expires_at > NOW() OR completed_at IS NOT NULL)
+	`, id, uploader(userID)).Scan(
+		&sess.ID, &sess.Kind, &sess.ContentType, &sess.Filename, &sess.Size, &sess.ChunkSize,
+		&sess.TotalChunks, &sess.Result, &sess.CreatedAt, &sess.ExpiresAt, &sess.CompletedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, "SELECT chunk_index FROM upload_session_chunks WHERE session_id = $1 ORDER BY chunk_index", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sess.Received = []int{}
+	for rows.Next() {
+		var index int
+		if err := rows.Scan(&index); err != nil {
+			return nil, err
+		}
+		sess.Received = append(sess.Received, index)
+	}
+
+	return &sess, rows.Err()
+}
+
+// expectedChunkSize returns the exact size of a chunk; only the last one may be shorter
+func (sess *Session) expectedChunkSize(index int) int64 {
+	if index == sess.TotalChunks-1 {
+		return sess.Size - int64(index)*int64(sess.ChunkSize)
+	}
+	return int64(sess.ChunkSize)
+}
+
+// PutChunk stores one chunk after verifying its size and SHA-256. Re-sending a chunk replaces it.
+func (s *Service) PutChunk(ctx context.Context, id string, index int, body io.Reader, checksum string, userID int64) (*Session, error) {
+	sess, err := s.GetSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sess.CompletedAt != nil {
+		return sess, nil
+	}
+	if index < 0 || index >= sess.TotalChunks {
+		return nil, ErrChunkInvalid
+	}
+
+	expected := sess.expectedChunkSize(index)
+	data, err := io.ReadAll(io.LimitReader(body, expected+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if int64(len(data)) != expected {
+		return nil, ErrChunkInvalid
+	}
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	if actual != checksum {
+		return nil, ErrChecksumMismatch
+	}
+
+	if err := s.storage.Put(ctx, chunkKey(id, index), bytes.NewReader(data), expected, "application/octet-stream"); err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+
+	_, err = s.db.Exec(ctx, `
+		INSERT INTO upload_session_chunks (session_id, chunk_index, size, checksum)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (session_id, chunk_index) DO UPDATE SET size = EXCLUDED.size, checksum = EXCLUDED.checksum, created_at = NOW()
+	`, id, index, expected, actual)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record chunk: %w", err)
+	}
+
+	return s.GetSession(ctx, id, userID)
+}
+
+// CompleteSession assembles the chunks into the final file.
+// Images go through the regular image pipeline; videos are validated by their header and
+// stored by content hash like every other upload. The session is claimed for assembleTimeout
+// instead of being locked, and the file is assembled detached from the request, so a client
+// that gives up can poll the session for the result. Completing twice returns the same result.
+func (s *Service) CompleteSession(ctx context.Context, id string, userID int64) (json.RawMessage, error) {
+	sess, err := s.GetSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sess.Result != nil {
+		return sess.Result, nil
+	}
+	if len(sess.Received) != sess.TotalChunks {
+		return nil, ErrSessionIncomplete
+	}
+
+	tag, err := s.db.Exec(ctx, `
+		UPDATE upload_sessions SET assembling_until = NOW() + $2::interval
+		WHERE id = $1 AND result IS NULL AND (assembling_until IS NULL OR assembling_until < NOW())
+	`, id, assembleTimeout.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim upload session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// Another request completed the session meanwhile, or is still assembling it
+		var stored json.RawMessage
+		if err := s.db.QueryRow(ctx, "SELECT result FROM upload_sessions WHERE id = $1", id).Scan(&stored); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrSessionNotFound
+			}
+			return nil, err
+		}
+		if stored != nil {
+			return stored, nil
+		}
+		return nil, ErrSessionBusy
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), assembleTimeout)
+	defer cancel()
+
+	resultJSON, checksums, err := s.assemble(ctx, sess, userID)
+	if err != nil {
+		if _, releaseErr := s.db.Exec(ctx, "UPDATE upload_sessions SET assembling_until = NULL WHERE id = $1", id); releaseErr != nil {
+			slog.Warn("failed to release upload session", "id", id, "error", releaseErr)
+		}
+		return nil, err
+	}
+
+	if _, err := s.db.Exec(ctx, `
+		UPDATE upload_sessions SET result = $1, completed_at = NOW(), assembling_until = NULL WHERE id = $2
+	`, resultJSON, id); err != nil {
+		return nil, err
+	}
+	s.deleteChunks(ctx, id, len(checksums))
+
+	return resultJSON, nil
+}
+
+// assemble runs the chunks of a complete session through the pipeline of its kind
+func (s *Service) assemble(ctx context.Context, sess *Session, userID int64) (json.RawMessage, []string, error) {
+	checksums, err := s.chunkChecksums(ctx, sess.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result any
+	switch sess.Kind {
+	case blobKindImage:
+		r := s.newChunkReader(ctx, sess.ID, checksums)
+		result, err = s.UploadImage(ctx, r, sess.ContentType, sess.Size, userID)
+		r.Close()
+	default:
+		result, err = s.assembleVideo(ctx, sess, checksums, userID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resultJSON, checksums, nil
+}
+
+// AbortSession discards a session and its chunks
+func (s *Service) AbortSession(ctx context.Context, id string, userID int64) error {
+	sess, err := s.GetSession(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	s.deleteChunks(ctx, id, sess.TotalChunks)
+	_, err = s.db.Exec(ctx, "DELETE FROM upload_sessions WHERE id = $1", id)
+	return err
+}
+
+func (s *Service) chunkChecksums(ctx context.Context, id string) ([]string, error) {
+	rows, err := s.db.Query(ctx, "SELECT checksum FROM upload_session_chunks WHERE session_id = $1 ORDER BY chunk_index", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checksums []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		checksums = append(checksums, c)
+	}
+	return checksums, rows.Err()
+}
+
+func (s *Service) deleteChunks(ctx context.Context, id string, total int) {
+	for i := 0; i < total; i++ {
+		s.storage.Delete(ctx, chunkKey(id, i))
+	}
+}
+
+// assembleVideo reads the chunks twice: once to validate the header and hash the content,
+// then to stream them into the content-addressed file
+func (s *Service) assembleVideo(ctx context.Context, sess *Session, checksums []string, userID int64) (*VideoResult, error) {
+	r := s.newChunkReader(ctx, sess.ID, checksums)
+	head := make([]byte, 4096)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		r.Close()
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if err := validateVideo(head[:n], sess.ContentType); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	h := sha256.New()
+	h.Write(head[:n])
+	_, err = io.Copy(h, r)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	existing, err := s.acquireBlob(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		var result VideoResult
+		if err := json.Unmarshal(existing.Result, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode stored upload result: %w", err)
+		}
+		if err := s.recordVideo(ctx, &result, userID); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+
+	if err := s.checkQuota(ctx, userID, sess.Size); err != nil {
+		return nil, err
+	}
+
+	key := "videos/" + hash + videoTypes[sess.ContentType]
+	r = s.newChunkReader(ctx, sess.ID, checksums)
+	err = s.storage.Put(ctx, key, r, sess.Size, sess.ContentType)
+	r.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+
+	result := &VideoResult{URL: s.url(key), ContentType: sess.ContentType, Size: sess.Size}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.registerBlob(ctx, &blob{Hash: hash, Kind: blobKindVideo, URL: result.URL, Size: sess.Size, Result: resultJSON}); err != nil {
+		return nil, err
+	}
+	if err := s.recordVideo(ctx, result, userID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// recordVideo adds a video upload to the media library
+func (s *Service) recordVideo(ctx context.Context, result *VideoResult, userID int64) error {
+	return s.recordMedia(ctx, &Media{
+		URL:        result.URL,
+		Kind:       blobKindVideo,
+		MIME:       result.ContentType,
+		Size:       result.Size,
+		UploadedBy: uploader(userID),
+	})
+}
+
+// chunkReader streams the chunks of a session in order, verifying each against its checksum
+type chunkReader struct {
+	ctx       context.Context
+	service   *Service
+	id        string
+	checksums []string
+	index     int
+	cur       io.ReadCloser
+	hash      hash.Hash
+}
+
+func (s *Service) newChunkReader(ctx context.Context, id string, checksums []string) *chunkReader {
+	return &chunkReader{ctx: ctx, service: s, id: id, checksums: checksums}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if r.index == len(r.checksums) {
+				return 0, io.EOF
+			}
+			cur, err := r.service.storage.Get(r.ctx, chunkKey(r.id, r.index))
+			if err != nil {
+				return 0, fmt.Errorf("failed to open chunk %d: %w", r.index, err)
+			}
+			r.cur = cur
+			r.hash = sha256.New()
+		}
+
+		n, err := r.cur.Read(p)
+		r.hash.Write(p[:n])
+		if errors.Is(err, io.EOF) {
+			r.cur.Close()
+			r.cur = nil
+			if hex.EncodeToString(r.hash.Sum(nil)) != r.checksums[r.index] {
+				return n, fmt.Errorf("chunk %d: %w", r.index, ErrChecksumMismatch)
+			}
+			r.index++
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
+
+// CleanupSessions removes expired sessions with their staged chunks, and incoming files
+// (chunks and never-completed direct uploads) that outlived any session. Returns the number of
+// sessions removed.
+func (s *Service) CleanupSessions(ctx context.Context) (int64, error) {
+	rows, err := s.db.Query(ctx, `
+		DELETE FROM upload_sessions
+		WHERE expires_at < NOW() AND (assembling_until IS NULL OR assembling_until < NOW())
+		RETURNING id, total_chunks
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var expired int64
+	for rows.Next() {
+		var id string
+		var total int
+		if err := rows.Scan(&id, &total); err != nil {
+			rows.Close()
+			return expired, err
+		}
+		s.deleteChunks(ctx, id, total)
+		expired++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return expired, err
+	}
+
+	// Anything staged before the oldest live session could have started is abandoned
+	objects, err := s.storage.List(ctx, incomingPrefix)
+	if err != nil {
+		return expired, err
+	}
+	cutoff := time.Now().Add(-sessionTTL)
+	for _, o := range objects {
+		if o.ModifiedAt.Before(cutoff) {
+			if err := s.storage.Delete(ctx, o.Key); err != nil {
+				return expired, err
+			}
+		}
+	}
+
+	return expired, nil
+}
+
+// RunSessionJob cleans up expired upload sessions and incoming files every interval until ctx is done
+func (s *Service) RunSessionJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n, err := s.CleanupSessions(ctx); err != nil {
+			slog.Error("upload session cleanup failed", "error", err)
+		} else if n > 0 {
+			slog.Info("expired upload sessions removed", "count", n)
+		}
+	}
+}
+
+// sessionError writes the response for errors returned by the session methods
+func sessionError(w http.ResponseWriter, err error, action string) {
+	if quotaError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		response.NotFound(w, "upload session not found")
+	case errors.Is(err, ErrSessionIncomplete):
+		response.Conflict(w, "upload session is missing chunks")
+	case errors.Is(err, ErrSessionBusy):
+		response.Conflict(w, "upload session is being assembled, poll it for the result")
+	case errors.Is(err, ErrChunkInvalid):
+		response.ValidationError(w, "invalid chunk index, chunk size or file size")
+	case errors.Is(err, ErrChecksumMismatch):
+		response.BadRequest(w, "chunk checksum mismatch")
+	case errors.Is(err, ErrInvalidFileType):
+		response.BadRequest(w, "invalid file type. Allowed: jpeg, png, webp, gif, webm, mp4")
+	case errors.Is(err, ErrFileTooLarge):
+		response.BadRequest(w, "file too large")
+	case errors.Is(err, ErrContentMismatch):
+		response.BadRequest(w, "file content does not match its type")
+	case errors.Is(err, ErrPolyglotDetected):
+		response.BadRequest(w, "file contains data that is not part of the media")
+	case errors.Is(err, ErrImageTooLarge):
+		response.BadRequest(w, "image dimensions too large")
+	default:
+		slog.Error("failed to "+action, "error", err)
+		response.InternalError(w, "failed to "+action)
+	}
+}
+
+// CreateSession handles POST /api/upload/sessions
+func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	sess, err := h.service.CreateSession(r.Context(), &req, userID)
+	if err != nil {
+		sessionError(w, err, "create upload session")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, sess)
+}
+
+// GetSession handles GET /api/upload/sessions/{id}
+func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	sess, err := h.service.GetSession(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		sessionError(w, err, "get upload session")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, sess)
+}
+
+// PutChunk handles PUT /api/upload/sessions/{id}/chunks/{index}.
+// The body is the raw chunk; X-Chunk-SHA256 carries its hex SHA-256.
+func (h *Handler) PutChunk(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil {
+		response.BadRequest(w, "invalid chunk index")
+		return
+	}
+
+	checksum := strings.ToLower(r.Header.Get("X-Chunk-SHA256"))
+	if len(checksum) != 64 {
+		response.BadRequest(w, "X-Chunk-SHA256 header is required")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	body := http.MaxBytesReader(w, r.Body, maxChunkSize+1)
+
+	sess, err := h.service.PutChunk(r.Context(), chi.URLParam(r, "id"), index, body, checksum, userID)
+	if err != nil {
+		sessionError(w, err, "store chunk")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, sess)
+}
+
+// CompleteSession handles POST /api/upload/sessions/{id}/complete
+// The write deadline is extended past the server-wide WriteTimeout, as assembling takes longer.
+func (h *Handler) CompleteSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(assembleTimeout + 10*time.Second)); err != nil {
+		slog.Warn("failed to extend write deadline", "error", err)
+	}
+
+	result, err := h.service.CompleteSession(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		sessionError(w, err, "complete upload")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// AbortSession handles DELETE /api/upload/sessions/{id}
+func (h *Handler) AbortSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	if err := h.service.AbortSession(r.Context(), chi.URLParam(r, "id"), userID); err != nil {
+		sessionError(w, err, "abort upload session")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "upload session aborted"})
+}
@@ -0,0 +1,345 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+
+	// maxSVGDepth stops deeply nested documents from exhausting the sanitizer
+	maxSVGDepth = 256
+)
+
+var (
+	errSVGDoctype = errors.New("DOCTYPE and entity declarations are not allowed")
+	errSVGRoot    = errors.New("root element must be <svg>")
+	errSVGDepth   = errors.New("document is nested too deeply")
+
+	// svgElements are kept with their content; anything else is dropped together with its subtree
+	// (script, foreignObject, iframe, animate/set that can rewrite attributes, ...)
+	svgElements = toSet(
+		"svg", "g", "defs", "symbol", "use", "title", "desc", "style",
+		"path", "rect", "circle", "ellipse", "line", "polyline", "polygon",
+		"text", "tspan", "textPath",
+		"linearGradient", "radialGradient", "stop", "clipPath", "mask", "pattern", "marker", "image",
+		"filter", "feBlend", "feColorMatrix", "feComponentTransfer", "feComposite", "feDropShadow",
+		"feFlood", "feFuncA", "feFuncB", "feFuncG", "feFuncR", "feGaussianBlur", "feMerge",
+		"feMergeNode", "feMorphology", "feOffset",
+	)
+
+	// svgTextElements keep whitespace-only text, which is significant inside them
+	svgTextElements = toSet("text", "tspan", "textPath", "title", "desc")
+
+	svgAttributes = toSet(
+		// Core and structure
+		"id", "class", "style", "lang", "xml:space", "version", "viewBox", "preserveAspectRatio",
+		"x", "y", "width", "height", "transform", "href", "xlink:href",
+		// Geometry
+		"d", "pathLength", "cx", "cy", "r", "rx", "ry", "x1", "y1", "x2", "y2", "points",
+		// Presentation
+		"fill", "fill-opacity", "fill-rule", "stroke", "stroke-width", "stroke-linecap",
+		"stroke-linejoin", "stroke-miterlimit", "stroke-dasharray", "stroke-dashoffset",
+		"stroke-opacity", "opacity", "color", "display", "visibility", "overflow",
+		"clip-path", "clip-rule", "mask", "filter", "vector-effect", "shape-rendering",
+		"paint-order", "mix-blend-mode", "isolation", "enable-background",
+		"marker-start", "marker-mid", "marker-end", "markerWidth", "markerHeight",
+		"markerUnits", "refX", "refY", "orient",
+		// Text
+		"font-family", "font-size", "font-weight", "font-style", "font-variant",
+		"text-anchor", "dominant-baseline", "alignment-baseline", "baseline-shift",
+		"letter-spacing", "word-spacing", "text-decoration", "dx", "dy", "rotate",
+		"textLength", "lengthAdjust", "startOffset", "method", "spacing", "side",
+		// Gradients, patterns, clipping, masking
+		"offset", "stop-color", "stop-opacity", "gradientUnits", "gradientTransform",
+		"spreadMethod", "fx", "fy", "fr", "patternUnits", "patternContentUnits",
+		"patternTransform", "clipPathUnits", "maskUnits", "maskContentUnits",
+		// Filters
+		"filterUnits", "primitiveUnits", "in", "in2", "result", "stdDeviation", "mode",
+		"type", "values", "operator", "k1", "k2", "k3", "k4", "radius",
+		"flood-color", "flood-opacity", "tableValues", "slope", "intercept",
+		"amplitude", "exponent", "color-interpolation-filters",
+		// Style element
+		"media",
+	)
+
+	// cssURL matches url(...) in attribute values and CSS; only fragment references are kept
+	cssURL        = regexp.MustCompile(`(?i)url\s*\(\s*(['"]?)\s*([^)'"]*?)\s*(['"]?)\s*\)`)
+	cssImport     = regexp.MustCompile(`(?i)@import[^;]*;?`)
+	cssImageSet   = regexp.MustCompile(`(?i)(-webkit-)?image-set\s*\(`)
+	cssDangerous  = regexp.MustCompile(`(?i)expression\s*\(|behavior\s*:|-moz-binding|javascript:`)
+	whitespace    = regexp.MustCompile(`\s+`)
+	rasterDataURI = regexp.MustCompile(`^data:image/(png|jpeg|gif|webp);base64,[A-Za-z0-9+/=\s]+$`)
+
+	svgTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	svgAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func toSet(items ...string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// sanitizeSVG parses an SVG document with an XML tokenizer and rebuilds it from allowlisted
+// elements and attributes only. External references (non-fragment href/url(), @import) are
+// removed, DOCTYPE and entity declarations are rejected, and comments, processing instructions,
+// metadata and insignificant whitespace are dropped, which also minifies the output.
+func sanitizeSVG(content []byte) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	dec.Strict = true
+
+	usesXLink := bytes.Contains(content, []byte("xlink:href"))
+
+	var out bytes.Buffer
+	var stack []string // Raw names of open elements, kept or not
+	skipDepth := 0     // > 0 while inside a dropped subtree
+	rootSeen := false
+	inStyle := false
+	var styleText strings.Builder
+
+	// The start tag is left open so that empty elements can be closed as <x/>
+	open := false
+	closeStart := func() {
+		if open {
+			out.WriteByte('>')
+			open = false
+		}
+	}
+
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.Directive:
+			return nil, errSVGDoctype
+
+		case xml.ProcInst, xml.Comment:
+			// Dropped
+
+		case xml.StartElement:
+			name := rawName(t.Name)
+			if len(stack) == 0 {
+				if rootSeen || name != "svg" {
+					return nil, errSVGRoot
+				}
+				rootSeen = true
+			}
+			stack = append(stack, name)
+			if len(stack) > maxSVGDepth {
+				return nil, errSVGDepth
+			}
+
+			if skipDepth > 0 || !svgElements[name] {
+				skipDepth++
+				continue
+			}
+
+			closeStart()
+			out.WriteByte('<')
+			out.WriteString(name)
+			writeSVGAttributes(&out, name, t.Attr, len(stack) == 1, usesXLink)
+			open = true
+			if name == "style" {
+				inStyle = true
+				styleText.Reset()
+			}
+
+		case xml.EndElement:
+			name := rawName(t.Name)
+			if len(stack) == 0 || stack[len(stack)-1] != name {
+				return nil, fmt.Errorf("unexpected end element </%s>", name)
+			}
+			stack = stack[:len(stack)-1]
+
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if name == "style" {
+				if css := sanitizeCSS(styleText.String()); css != "" {
+					closeStart()
+					svgTextEscaper.WriteString(&out, css)
+				}
+				inStyle = false
+			}
+			if open {
+				out.WriteString("/>")
+				open = false
+				continue
+			}
+			out.WriteString("</")
+			out.WriteString(name)
+			out.WriteByte('>')
+
+		case xml.CharData:
+			if skipDepth > 0 || len(stack) == 0 {
+				continue
+			}
+			if inStyle {
+				styleText.Write(t)
+				continue
+			}
+			if len(bytes.TrimSpace(t)) == 0 && !svgTextElements[stack[len(stack)-1]] {
+				continue
+			}
+			closeStart()
+			svgTextEscaper.WriteString(&out, string(t))
+		}
+	}
+
+	if !rootSeen {
+		return nil, errSVGRoot
+	}
+	if len(stack) != 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return out.Bytes(), nil
+}
+
+// rawName returns the element or attribute name as written, including its prefix
+func rawName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// writeSVGAttributes writes the allowlisted attributes of an element with safe values
+// On the root element namespace declarations are added when missing; xlink only when the document uses it.
+func writeSVGAttributes(out *bytes.Buffer, element string, attrs []xml.Attr, root, xlink bool) {
+	seen := map[string]bool{}
+	for _, a := range attrs {
+		name := rawName(a.Name)
+		value := strings.TrimSpace(whitespace.ReplaceAllString(a.Value, " "))
+		if seen[name] {
+			continue
+		}
+
+		switch {
+		case name == "xmlns":
+			if value != svgNamespace {
+				continue
+			}
+		case name == "xmlns:xlink":
+			if value != xlinkNamespace {
+				continue
+			}
+		case !svgAttributes[name]:
+			continue
+		case name == "href" || name == "xlink:href":
+			if !safeHref(element, value) {
+				continue
+			}
+		case name == "style":
+			value = sanitizeCSS(value)
+			if value == "" {
+				continue
+			}
+		default:
+			if !safeAttributeValue(value) {
+				continue
+			}
+		}
+
+		seen[name] = true
+		out.WriteByte(' ')
+		out.WriteString(name)
+		out.WriteString(`="`)
+		svgAttrEscaper.WriteString(out, value)
+		out.WriteByte('"')
+	}
+
+	// Needed to render the file on its own (<img src>, direct links) and to keep xlink:href valid
+	if root && !seen["xmlns"] {
+		out.WriteString(` xmlns="` + svgNamespace + `"`)
+	}
+	if root && xlink && !seen["xmlns:xlink"] {
+		out.WriteString(` xmlns:xlink="` + xlinkNamespace + `"`)
+	}
+}
+
+// safeHref allows references to elements of the same document, and inline raster data for <image>
+func safeHref(element, value string) bool {
+	if strings.HasPrefix(value, "#") {
+		return true
+	}
+	return element == "image" && rasterDataURI.MatchString(value)
+}
+
+// safeAttributeValue rejects script URLs and url() references outside the document
+func safeAttributeValue(value string) bool {
+	compact := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, value))
+	if strings.Contains(compact, "javascript:") || strings.Contains(compact, "vbscript:") ||
+		cssDangerous.MatchString(compact) || cssImageSet.MatchString(compact) {
+		return false
+	}
+	for _, m := range cssURL.FindAllStringSubmatch(value, -1) {
+		if !strings.HasPrefix(m[2], "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeCSS removes @import rules, image-set() (which loads plain strings as URLs), declarations
+// that can execute code and url() references outside the document. Used for both <style> content
+// and style attributes.
+func sanitizeCSS(css string) string {
+	css = cssImport.ReplaceAllString(css, "")
+	css = stripImageSet(css)
+	css = cssURL.ReplaceAllStringFunc(css, func(m string) string {
+		if strings.HasPrefix(cssURL.FindStringSubmatch(m)[2], "#") {
+			return m
+		}
+		return "none"
+	})
+	// Escapes could spell out any of the above, so CSS containing them is dropped entirely
+	if strings.Contains(css, `\`) || cssDangerous.MatchString(css) {
+		return ""
+	}
+	return strings.TrimSpace(whitespace.ReplaceAllString(css, " "))
+}
+
+// stripImageSet replaces every image-set() call, including its nested arguments, with none
+func stripImageSet(css string) string {
+	for {
+		loc := cssImageSet.FindStringIndex(css)
+		if loc == nil {
+			return css
+		}
+		depth, end := 1, len(css)
+		for i := loc[1]; i < len(css); i++ {
+			if css[i] == '(' {
+				depth++
+			} else if css[i] == ')' {
+				if depth--; depth == 0 {
+					end = i + 1
+					break
+				}
+			}
+		}
+		css = css[:loc[0]] + "none" + css[end:]
+	}
+}
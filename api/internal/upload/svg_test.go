@@ -0,0 +1,139 @@
+package upload
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSanitizeSVG(t *testing.T) {
+	const open = `<svg xmlns="http://www.w3.org/2000/svg">`
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "script element and event handlers",
+			in:   open + `<script>alert(1)</script><rect width="10" onload="alert(1)" onClick="alert(2)"/></svg>`,
+			want: open + `<rect width="10"/></svg>`,
+		},
+		{
+			name: "script in CDATA",
+			in:   open + `<script><![CDATA[alert(1)]]></script><circle r="1"/></svg>`,
+			want: open + `<circle r="1"/></svg>`,
+		},
+		{
+			name: "foreignObject with HTML",
+			in:   `<svg><foreignObject><iframe src="javascript:alert(1)"/><body onload="alert(1)"/></foreignObject><circle r="1"/></svg>`,
+			want: open + `<circle r="1"/></svg>`,
+		},
+		{
+			name: "animation rewriting href",
+			in:   open + `<a href="#x"><set attributeName="href" to="javascript:alert(1)"/></a><animate attributeName="href" values="javascript:alert(1)"/></svg>`,
+			want: `<svg xmlns="http://www.w3.org/2000/svg"/>`,
+		},
+		{
+			name: "external use href",
+			in:   `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><use href="https://evil.example/sprite.svg#icon"/><use xlink:href="data:image/svg+xml;base64,PHN2Zz4="/><use xlink:href="#icon"/></svg>`,
+			want: `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><use/><use/><use xlink:href="#icon"/></svg>`,
+		},
+		{
+			name: "javascript in xlink:href",
+			in:   `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><image xlink:href="javascript:alert(1)"/></svg>`,
+			want: `<svg xmlns:xlink="http://www.w3.org/1999/xlink" xmlns="http://www.w3.org/2000/svg"><image/></svg>`,
+		},
+		{
+			name: "entity-encoded javascript",
+			in:   open + `<image href="&#106;ava&#x73;cript:alert(1)"/><rect fill="&#x6A;avascript:alert(1)" stroke="url(&#104;ttps://evil.example/x)"/></svg>`,
+			want: open + `<image/><rect/></svg>`,
+		},
+		{
+			name: "javascript split by whitespace",
+			in:   open + `<rect fill="java&#9;script:alert(1)" stroke="red"/></svg>`,
+			want: open + `<rect stroke="red"/></svg>`,
+		},
+		{
+			name: "inline raster image is kept",
+			in:   open + `<image href="data:image/png;base64,iVBORw0KGgo=" width="1"/><image href="data:text/html;base64,PHNjcmlwdD4="/></svg>`,
+			want: open + `<image href="data:image/png;base64,iVBORw0KGgo=" width="1"/><image/></svg>`,
+		},
+		{
+			name: "style element with url, import and image-set",
+			in: open + `<style>@import url(https://evil.example/a.css);` +
+				`.a{fill:url(#grad);background:url("https://evil.example/x.png")}` +
+				`.b{background-image:image-set("https://evil.example/x.png" 1x, url(https://evil.example/y.png) 2x)}` +
+				`.c{background:-webkit-image-set('//evil.example/x.png' 1x)}</style></svg>`,
+			want: open + `<style>.a{fill:url(#grad);background:none}.b{background-image:none}.c{background:none}</style></svg>`,
+		},
+		{
+			name: "style element in CDATA",
+			in:   open + `<style><![CDATA[@import "https://evil.example/a.css"; .a{fill:red}]]></style></svg>`,
+			want: open + `<style>.a{fill:red}</style></svg>`,
+		},
+		{
+			name: "style element with CSS escapes is dropped",
+			in:   open + `<style>.a{background:\75 rl(https://evil.example/x.png)}</style><rect/></svg>`,
+			want: open + `<style/><rect/></svg>`,
+		},
+		{
+			name: "style attribute",
+			in:   open + `<rect style="fill:red;background:image-set('https://evil.example/x.png' 1x)"/><rect style="width:expression(alert(1))"/></svg>`,
+			want: open + `<rect style="fill:red;background:none"/><rect/></svg>`,
+		},
+		{
+			name: "image-set in presentation attribute",
+			in:   open + `<rect fill="image-set('https://evil.example/x.png' 1x)"/></svg>`,
+			want: open + `<rect/></svg>`,
+		},
+		{
+			name: "text in CDATA is escaped",
+			in:   open + `<text x="1"><![CDATA[<script>alert(1)</script>]]></text></svg>`,
+			want: open + `<text x="1">&lt;script&gt;alert(1)&lt;/script&gt;</text></svg>`,
+		},
+		{
+			name: "comments, processing instructions and metadata are dropped",
+			in:   `<?xml version="1.0"?><!-- editor --><svg xmlns="http://www.w3.org/2000/svg"><metadata><rdf/></metadata><g>  <path d="M0 0"/>  </g></svg>`,
+			want: open + `<g><path d="M0 0"/></g></svg>`,
+		},
+		{
+			name:    "billion laughs",
+			in:      `<?xml version="1.0"?><!DOCTYPE svg [<!ENTITY a "lol"><!ENTITY b "&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;">]><svg>&b;</svg>`,
+			wantErr: errSVGDoctype,
+		},
+		{
+			name:    "external entity",
+			in:      `<!DOCTYPE svg [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><svg><text>&xxe;</text></svg>`,
+			wantErr: errSVGDoctype,
+		},
+		{
+			name:    "HTML root",
+			in:      `<html><body><svg/></body></html>`,
+			wantErr: errSVGRoot,
+		},
+		{
+			name:    "second root element",
+			in:      `<svg/><script>alert(1)</script>`,
+			wantErr: errSVGRoot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sanitizeSVG([]byte(tt.in))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/itam-misis/itam-api/internal/audit"
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+var (
+	ErrInvalidTransform  = errors.New("invalid crop or rotation")
+	ErrInvalidFocalPoint = errors.New("focal point must be within the image")
+	ErrNotTransformable  = errors.New("media cannot be transformed")
+)
+
+// minCropSize is the smallest side of a cropped image in pixels
+const minCropSize = 16
+
+// CropRect selects a part of the (rotated) image, relative to its size
+type CropRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// TransformRequest describes a derived image. Rotation (clockwise, multiple of 90) is applied
+// before the crop; the focal point is relative to the result.
+type TransformRequest struct {
+	Rotate     int         `json:"rotate"`
+	Crop       *CropRect   `json:"crop,omitempty"`
+	FocalPoint *FocalPoint `json:"focal_point,omitempty"`
+	AltText    *string     `json:"alt_text,omitempty"`
+}
+
+// rotations maps clockwise rotation angles to the EXIF orientation that produces them
+var rotations = map[int]int{
+	0:   orientationNormal,
+	90:  orientationRotate90,
+	180: orientationRotate180,
+	270: orientationRotate270,
+}
+
+func (req *TransformRequest) validate() error {
+	req.Rotate = ((req.Rotate % 360) + 360) % 360
+	if _, ok := rotations[req.Rotate]; !ok {
+		return ErrInvalidTransform
+	}
+	if c := req.Crop; c != nil {
+		if c.X < 0 || c.Y < 0 || c.Width <= 0 || c.Height <= 0 || c.X+c.Width > 1.0001 || c.Y+c.Height > 1.0001 {
+			return ErrInvalidTransform
+		}
+	}
+	if req.FocalPoint != nil && !req.FocalPoint.valid() {
+		return ErrInvalidFocalPoint
+	}
+	return nil
+}
+
+// key identifies the derived content: the same transform of the same image is stored once
+func (req *TransformRequest) key(source string) string {
+	k := fmt.Sprintf("%s|rotate=%d", source, req.Rotate)
+	if c := req.Crop; c != nil {
+		k += fmt.Sprintf("|crop=%.4f,%.4f,%.4f,%.4f", c.X, c.Y, c.Width, c.Height)
+	}
+	return k
+}
+
+// TransformMedia rotates and crops a stored image into a new upload with its own renditions.
+// The original is left untouched; the result is added to the media library with the alt text
+// and focal point, and links back to its source.
+func (s *Service) TransformMedia(ctx context.Context, id int64, req *TransformRequest, userID int64, ip string) (*Media, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	source, err := s.GetMedia(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Animated GIFs would lose their frames
+	if source.Kind != blobKindImage || source.MIME == formatMIMETypes[FormatGIF] {
+		return nil, ErrNotTransformable
+	}
+
+	key, err := s.keyFromURL(source.URL)
+	if err != nil {
+		return nil, ErrNotTransformable
+	}
+
+	hash := contentHash([]byte(req.key(fileKey(source.URL))))
+
+	existing, err := s.acquireBlob(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ImageResult
+	if existing != nil {
+		if err := json.Unmarshal(existing.Result, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode stored upload result: %w", err)
+		}
+	} else {
+		if err := s.checkQuota(ctx, userID, source.Size); err != nil {
+			return nil, err
+		}
+		renditions, err := s.renderTransform(ctx, key, req)
+		if err != nil {
+			return nil, err
+		}
+
+		saved, err := s.saveRenditions(ctx, hash, renditions)
+		if err != nil {
+			return nil, err
+		}
+		result = *saved
+
+		var total int64
+		for _, v := range result.Variants {
+			total += v.Size
+		}
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.registerBlob(ctx, &blob{Hash: hash, Kind: blobKindImage, URL: result.URL, Size: total, Result: resultJSON}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recordImage(ctx, &result, userID); err != nil {
+		return nil, err
+	}
+
+	// Alt text is inherited from the source unless given
+	altText := source.AltText
+	if req.AltText != nil {
+		altText = nil
+		if trimmed := strings.TrimSpace(*req.AltText); trimmed != "" {
+			altText = &trimmed
+		}
+	}
+	var focalX, focalY *float64
+	if req.FocalPoint != nil {
+		focalX, focalY = &req.FocalPoint.X, &req.FocalPoint.Y
+	}
+
+	var derivedID int64
+	err = s.db.QueryRow(ctx, `
+		UPDATE media SET alt_text = $1, focal_x = $2, focal_y = $3, derived_from = $4
+		WHERE url = $5
+		RETURNING id
+	`, altText, focalX, focalY, source.ID, result.URL).Scan(&derivedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record derived media: %w", err)
+	}
+
+	derived, err := s.GetMedia(ctx, derivedID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityMedia, &derivedID, map[string]any{
+		"source":    source.URL,
+		"url":       derived.URL,
+		"transform": req,
+	}, ip)
+
+	return derived, nil
+}
+
+// renderTransform loads the main rendition stored at key, rotates and crops it,
+// and encodes the result like a regular upload
+func (s *Service) renderTransform(ctx context.Context, key string, req *TransformRequest) ([]rendition, error) {
+	file, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source image: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source image: %w", err)
+	}
+
+	// Stored renditions are already upright and within the upload limits
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode source image: %w", err)
+	}
+
+	img = applyOrientation(img, rotations[req.Rotate])
+
+	if c := req.Crop; c != nil {
+		b := img.Bounds()
+		rect := image.Rect(
+			b.Min.X+int(c.X*float64(b.Dx())),
+			b.Min.Y+int(c.Y*float64(b.Dy())),
+			b.Min.X+int((c.X+c.Width)*float64(b.Dx())),
+			b.Min.Y+int((c.Y+c.Height)*float64(b.Dy())),
+		).Intersect(b)
+		if rect.Dx() < minCropSize || rect.Dy() < minCropSize {
+			return nil, ErrInvalidTransform
+		}
+		img = toNRGBA(img).SubImage(rect)
+	}
+
+	return renderImage(img)
+}
+
+// TransformMedia handles POST /api/media/:id/transform
+func (h *Handler) TransformMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid media ID")
+		return
+	}
+
+	var req TransformRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	media, err := h.service.TransformMedia(r.Context(), id, &req, userID, r.RemoteAddr)
+	if quotaError(w, err) {
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMediaNotFound):
+			response.NotFound(w, "media not found")
+		case errors.Is(err, ErrInvalidTransform):
+			response.ValidationError(w, "rotate must be a multiple of 90 and crop must lie within the image")
+		case errors.Is(err, ErrInvalidFocalPoint):
+			response.ValidationError(w, "focal_point coordinates must be between 0 and 1")
+		case errors.Is(err, ErrNotTransformable):
+			response.BadRequest(w, "only static uploaded images can be cropped or rotated")
+		default:
+			slog.Error("failed to transform media", "error", err)
+			response.InternalError(w, "failed to transform media")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, media)
+}
@@ -1,171 +1,344 @@
 package upload
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
-	"regexp"
+	"path"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/itam-misis/itam-api/internal/audit"
+	"github.com/itam-misis/itam-api/internal/auth"
 	"github.com/itam-misis/itam-api/internal/response"
+	"github.com/itam-misis/itam-api/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	ErrInvalidFileType = errors.New("invalid file type")
 	ErrFileTooLarge    = errors.New("file too large")
 	ErrFileNotFound    = errors.New("file not found")
-
-	// Allowed image types
-	allowedImageTypes = map[string]string{
-		"image/jpeg": ".jpg",
-		"image/png":  ".png",
-		"image/webp": ".webp",
-		"image/gif":  ".gif",
-	}
-
-	// SVG dangerous patterns
-	svgDangerousPatterns = regexp.MustCompile(`(?i)<script|javascript:|on\w+\s*=|xlink:href\s*=\s*["']?\s*data:|href\s*=\s*["']?\s*javascript:`)
 )
 
+const maxSVGSize = 1024 * 1024
+
 type Config struct {
-	UploadPath string
-	MaxSize    int64
-	BaseURL    string // e.g., "/uploads"
+	MaxSize      int64
+	MaxVideoSize int64
+	BaseURL      string           // Public URL of the storage root, e.g. "/uploads"
+	Quotas       map[string]int64 // Bytes per user by role, 0 or missing is unlimited
+	TotalQuota   int64            // Bytes for all uploads, 0 is unlimited
 }
 
 type Service struct {
-	config Config
+	db      *pgxpool.Pool
+	audit   *audit.Service
+	storage storage.Storage
+	config  Config
 }
 
-func NewService(cfg Config) *Service {
-	// Ensure directories exist
-	os.MkdirAll(filepath.Join(cfg.UploadPath, "images"), 0755)
-	os.MkdirAll(filepath.Join(cfg.UploadPath, "svg"), 0755)
-	return &Service{config: cfg}
+// NewService creates an upload service. Files are stored under "images/" and "svg/" keys
+// in store and served at BaseURL + "/" + key.
+func NewService(db *pgxpool.Pool, auditService *audit.Service, store storage.Storage, cfg Config) *Service {
+	return &Service{db: db, audit: auditService, storage: store, config: cfg}
 }
 
-// UploadImage handles image upload
-func (s *Service) UploadImage(file io.Reader, contentType string, size int64) (string, error) {
+// UploadImage handles image upload.
+// Images are decoded, oriented and re-encoded into responsive renditions; animated GIFs are stored as-is.
+// Files are named by the SHA-256 of the uploaded content, so uploading the same file again
+// returns the existing result and adds a reference instead of storing a copy.
+// Every upload is recorded in the media library with userID as the uploader.
+func (s *Service) UploadImage(ctx context.Context, file io.Reader, contentType string, size int64, userID int64) (*ImageResult, error) {
 	// Check file size
 	if size > s.config.MaxSize {
-		return "", ErrFileTooLarge
+		return nil, ErrFileTooLarge
 	}
 
-	// Check content type
-	ext, ok := allowedImageTypes[contentType]
-	if !ok {
-		return "", ErrInvalidFileType
+	data, err := io.ReadAll(io.LimitReader(file, s.config.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > s.config.MaxSize {
+		return nil, ErrFileTooLarge
 	}
 
-	// Generate unique filename
-	filename := generateUUID() + ext
-	filePath := filepath.Join(s.config.UploadPath, "images", filename)
+	// The real format comes from the content; contentType is only checked against it
+	format, err := validateImage(data, contentType)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create file
-	dst, err := os.Create(filePath)
+	hash := contentHash(data)
+
+	// Same content already stored
+	existing, err := s.acquireBlob(ctx, hash)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
+		return nil, err
+	}
+	if existing != nil {
+		var result ImageResult
+		if err := json.Unmarshal(existing.Result, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode stored upload result: %w", err)
+		}
+		// Content stored before the media library existed has no row yet
+		if err := s.recordImage(ctx, &result, userID); err != nil {
+			return nil, err
+		}
+		return &result, nil
 	}
-	defer dst.Close()
 
-	// Copy content
-	if _, err := io.Copy(dst, file); err != nil {
-		os.Remove(filePath)
-		return "", fmt.Errorf("failed to save file: %w", err)
+	if err := s.checkQuota(ctx, userID, int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	var renditions []rendition
+	if format == FormatGIF {
+		// Re-encoding a GIF would drop its animation frames, so every frame is decoded to validate it
+		cfg, err := validateGIF(data)
+		if err != nil {
+			return nil, err
+		}
+		renditions = []rendition{{format: FormatGIF, width: cfg.Width, height: cfg.Height, data: data}}
+	} else {
+		renditions, err = processImage(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := s.saveRenditions(ctx, hash, renditions)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.config.BaseURL + "/images/" + filename, nil
+	var total int64
+	for _, v := range result.Variants {
+		total += v.Size
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.registerBlob(ctx, &blob{Hash: hash, Kind: blobKindImage, URL: result.URL, Size: total, Result: resultJSON}); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordImage(ctx, result, userID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// UploadSVG handles SVG upload with sanitization
-func (s *Service) UploadSVG(file io.Reader, size int64) (string, error) {
+// recordImage adds an image upload to the media library
+func (s *Service) recordImage(ctx context.Context, result *ImageResult, userID int64) error {
+	main := result.Variants[0]
+	return s.recordMedia(ctx, &Media{
+		URL:        result.URL,
+		Kind:       blobKindImage,
+		MIME:       formatMIMETypes[main.Format],
+		Size:       main.Size,
+		Width:      &result.Width,
+		Height:     &result.Height,
+		UploadedBy: uploader(userID),
+	})
+}
+
+// saveRenditions writes image renditions to storage. The first rendition is the main image.
+func (s *Service) saveRenditions(ctx context.Context, base string, renditions []rendition) (*ImageResult, error) {
+	var written []string
+	variants := make([]ImageVariant, 0, len(renditions))
+
+	for _, r := range renditions {
+		key := "images/" + base + r.suffix + formatExtensions[r.format]
+
+		if err := s.storage.Put(ctx, key, bytes.NewReader(r.data), int64(len(r.data)), formatMIMETypes[r.format]); err != nil {
+			for _, k := range written {
+				s.storage.Delete(ctx, k)
+			}
+			return nil, fmt.Errorf("failed to save file: %w", err)
+		}
+		written = append(written, key)
+
+		variants = append(variants, ImageVariant{
+			URL:    s.url(key),
+			Width:  r.width,
+			Height: r.height,
+			Format: r.format,
+			Size:   int64(len(r.data)),
+		})
+	}
+
+	main := variants[0]
+	return &ImageResult{
+		URL:      main.URL,
+		Width:    main.Width,
+		Height:   main.Height,
+		Variants: variants,
+		Srcset:   buildSrcset(variants),
+	}, nil
+}
+
+// UploadSVG handles SVG upload with sanitization.
+// The sanitized content is stored by its SHA-256, so identical logos are stored once.
+func (s *Service) UploadSVG(ctx context.Context, file io.Reader, size int64, userID int64) (string, error) {
 	// Check file size (1MB max for SVG)
-	if size > 1024*1024 {
+	if size > maxSVGSize {
 		return "", ErrFileTooLarge
 	}
 
 	// Read content
-	content, err := io.ReadAll(file)
+	content, err := io.ReadAll(io.LimitReader(file, maxSVGSize+1))
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
+	if len(content) > maxSVGSize {
+		return "", ErrFileTooLarge
+	}
+
+	// Rebuild the document from allowlisted elements and attributes
+	sanitized, err := sanitizeSVG(content)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFileType, err)
+	}
+	hash := contentHash(sanitized)
 
-	// Basic SVG validation
-	contentStr := string(content)
-	if !strings.Contains(contentStr, "<svg") {
-		return "", ErrInvalidFileType
+	existing, err := s.acquireBlob(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		if err := s.recordSVG(ctx, existing.URL, existing.Size, userID); err != nil {
+			return "", err
+		}
+		return existing.URL, nil
 	}
 
-	// Sanitize SVG
-	sanitized := sanitizeSVG(contentStr)
+	if err := s.checkQuota(ctx, userID, int64(len(sanitized))); err != nil {
+		return "", err
+	}
 
-	// Generate unique filename
-	filename := generateUUID() + ".svg"
-	filePath := filepath.Join(s.config.UploadPath, "svg", filename)
+	key := "svg/" + hash + ".svg"
 
 	// Write sanitized content
-	if err := os.WriteFile(filePath, []byte(sanitized), 0644); err != nil {
+	if err := s.storage.Put(ctx, key, bytes.NewReader(sanitized), int64(len(sanitized)), "image/svg+xml"); err != nil {
 		return "", fmt.Errorf("failed to save file: %w", err)
 	}
 
-	return s.config.BaseURL + "/svg/" + filename, nil
-}
+	url := s.url(key)
+	if err := s.registerBlob(ctx, &blob{Hash: hash, Kind: blobKindSVG, URL: url, Size: int64(len(sanitized))}); err != nil {
+		return "", err
+	}
 
-// DeleteFile deletes a file by URL
-func (s *Service) DeleteFile(fileURL string) error {
-	// Extract path from URL
-	if !strings.HasPrefix(fileURL, s.config.BaseURL) {
-		return ErrFileNotFound
+	if err := s.recordSVG(ctx, url, int64(len(sanitized)), userID); err != nil {
+		return "", err
 	}
 
-	relativePath := strings.TrimPrefix(fileURL, s.config.BaseURL)
-	filePath := filepath.Join(s.config.UploadPath, relativePath)
+	return url, nil
+}
 
-	// Security check - ensure we're still within upload path
-	absPath, err := filepath.Abs(filePath)
+// recordSVG adds an SVG upload to the media library
+func (s *Service) recordSVG(ctx context.Context, url string, size int64, userID int64) error {
+	return s.recordMedia(ctx, &Media{
+		URL:        url,
+		Kind:       blobKindSVG,
+		MIME:       "image/svg+xml",
+		Size:       size,
+		UploadedBy: uploader(userID),
+	})
+}
+
+// DeleteFile drops one reference to the file at URL.
+// Files are removed from disk only when no other upload references the same content;
+// removing the last reference of a file an entity still points at fails with ErrMediaInUse.
+func (s *Service) DeleteFile(ctx context.Context, fileURL string) error {
+	key, err := s.keyFromURL(fileURL)
 	if err != nil {
 		return err
 	}
-	absUploadPath, _ := filepath.Abs(s.config.UploadPath)
-	if !strings.HasPrefix(absPath, absUploadPath) {
-		return ErrFileNotFound
+
+	if _, err := s.storage.Stat(ctx, key); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrFileNotFound
+		}
+		return err
 	}
 
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return ErrFileNotFound
+	tracked, err := s.releaseBlob(ctx, fileURL, func() error {
+		return s.removeWithRenditions(ctx, key)
+	})
+	if err != nil {
+		return err
+	}
+	if !tracked {
+		// Uploaded before deduplication, no other upload can reference it
+		inUse, err := isReferenced(ctx, s.db, fileKey(fileURL))
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrMediaInUse
+		}
+		if _, err := s.db.Exec(ctx, "DELETE FROM media WHERE url = $1", fileURL); err != nil {
+			return err
+		}
+		return s.removeWithRenditions(ctx, key)
 	}
 
-	return os.Remove(filePath)
+	return nil
 }
 
-// sanitizeSVG removes potentially dangerous elements from SVG
-func sanitizeSVG(content string) string {
-	// Remove dangerous patterns
-	sanitized := svgDangerousPatterns.ReplaceAllString(content, "")
-
-	// Remove script tags entirely
-	scriptPattern := regexp.MustCompile(`(?i)<script[^>]*>[\s\S]*?</script>`)
-	sanitized = scriptPattern.ReplaceAllString(sanitized, "")
+// url returns the public URL of a storage key
+func (s *Service) url(key string) string {
+	return s.config.BaseURL + "/" + key
+}
 
-	// Remove event handlers (onclick, onload, etc.)
-	eventPattern := regexp.MustCompile(`(?i)\s+on\w+\s*=\s*["'][^"']*["']`)
-	sanitized = eventPattern.ReplaceAllString(sanitized, "")
+// keyFromURL maps an upload URL back to its storage key
+func (s *Service) keyFromURL(fileURL string) (string, error) {
+	key, ok := strings.CutPrefix(fileURL, s.config.BaseURL+"/")
+	if !ok || key == "" || strings.Contains(key, "..") {
+		return "", ErrFileNotFound
+	}
+	return key, nil
+}
 
-	return sanitized
+// storedKeys returns every stored key for the upload stem, e.g. "images/<hash>",
+// including its responsive and WebP renditions
+func (s *Service) storedKeys(ctx context.Context, stem string) ([]string, error) {
+	objects, err := s.storage.List(ctx, stem)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(objects))
+	for _, o := range objects {
+		// Skip other uploads that merely share the prefix
+		rest := strings.TrimPrefix(o.Key, stem)
+		if strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "-") {
+			keys = append(keys, o.Key)
+		}
+	}
+	return keys, nil
 }
 
-// generateUUID generates a random UUID-like string
-func generateUUID() string {
-	bytes := make([]byte, 16)
-	rand.Read(bytes)
-	return hex.EncodeToString(bytes)
+// removeWithRenditions deletes a file and the responsive renditions derived from it
+func (s *Service) removeWithRenditions(ctx context.Context, key string) error {
+	keys, err := s.storedKeys(ctx, strings.TrimSuffix(key, path.Ext(key)))
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := s.storage.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Handler
@@ -192,23 +365,27 @@ func (h *Handler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Get content type
+	// Declared type is only cross-checked, the format is detected from the content
 	contentType := header.Header.Get("Content-Type")
-	if contentType == "" {
-		// Try to detect from file
-		buffer := make([]byte, 512)
-		n, _ := file.Read(buffer)
-		contentType = http.DetectContentType(buffer[:n])
-		file.Seek(0, 0) // Reset reader
-	}
 
-	url, err := h.service.UploadImage(file, contentType, header.Size)
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.UploadImage(r.Context(), file, contentType, header.Size, userID)
+	if quotaError(w, err) {
+		return
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrInvalidFileType):
 			response.BadRequest(w, "invalid file type, allowed: jpg, png, webp, gif")
 		case errors.Is(err, ErrFileTooLarge):
 			response.BadRequest(w, "file too large, max 5MB")
+		case errors.Is(err, ErrContentMismatch):
+			response.BadRequest(w, "file content does not match its type")
+		case errors.Is(err, ErrPolyglotDetected):
+			response.BadRequest(w, "file contains data that is not part of the image")
+		case errors.Is(err, ErrImageTooLarge):
+			response.BadRequest(w, "image dimensions too large, max 12000px per side and 40 megapixels")
 		default:
 			slog.Error("failed to upload image", "error", err)
 			response.InternalError(w, "failed to upload image")
@@ -216,7 +393,7 @@ func (h *Handler) UploadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.JSON(w, http.StatusOK, map[string]string{"url": url})
+	response.JSON(w, http.StatusOK, result)
 }
 
 // UploadSVG handles POST /api/upload/svg
@@ -233,7 +410,12 @@ func (h *Handler) UploadSVG(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	url, err := h.service.UploadSVG(file, header.Size)
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	url, err := h.service.UploadSVG(r.Context(), file, header.Size, userID)
+	if quotaError(w, err) {
+		return
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrInvalidFileType):
@@ -260,15 +442,19 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	// Try images first, then svg
 	var err error
-	err = h.service.DeleteFile("/uploads/images/" + filename)
+	err = h.service.DeleteFile(r.Context(), h.service.url("images/"+filename))
 	if errors.Is(err, ErrFileNotFound) {
-		err = h.service.DeleteFile("/uploads/svg/" + filename)
+		err = h.service.DeleteFile(r.Context(), h.service.url("svg/"+filename))
 	}
 
 	if errors.Is(err, ErrFileNotFound) {
 		response.NotFound(w, "file not found")
 		return
 	}
+	if errors.Is(err, ErrMediaInUse) {
+		response.Conflict(w, "file is still used, remove it from all entities first")
+		return
+	}
 	if err != nil {
 		slog.Error("failed to delete file", "error", err)
 		response.InternalError(w, "failed to delete file")
@@ -0,0 +1,260 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"mime"
+	"regexp"
+)
+
+var (
+	ErrContentMismatch  = errors.New("file content does not match its declared type")
+	ErrImageTooLarge    = errors.New("image dimensions exceed the limit")
+	ErrPolyglotDetected = errors.New("file contains embedded markup or trailing data")
+
+	// declaredFormats maps client-sent content types to formats; aliases seen in the wild included
+	declaredFormats = map[string]string{
+		"image/jpeg":  FormatJPEG,
+		"image/jpg":   FormatJPEG,
+		"image/pjpeg": FormatJPEG,
+		"image/png":   FormatPNG,
+		"image/webp":  FormatWebP,
+		"image/gif":   FormatGIF,
+	}
+
+	// markupPattern finds content that browsers would render or execute if the file were
+	// ever served or sniffed as HTML. Markers are long enough not to occur by chance in compressed data.
+	markupPattern = regexp.MustCompile(`(?i)<(script|html[\s>]|body[\s>]|iframe|object[\s>]|embed[\s>]|!doctype|\?php|svg[\s>]|meta[\s>])|javascript:`)
+
+	pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+)
+
+const (
+	// maxImageDimension limits the width and height of uploaded images
+	maxImageDimension = 12000
+	// maxImagePixels limits the decoded size of a single frame (40 MP is ~160 MB as RGBA)
+	maxImagePixels = 40_000_000
+	// maxGIFPixels limits the total decoded size of all frames of an animated GIF
+	maxGIFPixels = 100_000_000
+)
+
+// detectFormat identifies an image format from its magic bytes
+func detectFormat(data []byte) string {
+	switch {
+	case len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
+		return FormatJPEG
+	case bytes.HasPrefix(data, pngSignature):
+		return FormatPNG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return FormatWebP
+	}
+	return ""
+}
+
+// validateImage checks an uploaded image before it is decoded:
+// the format comes from the content, never from the client, and must match the declared
+// content type when one is given (empty and application/octet-stream declare nothing);
+// files carrying markup, or data after the image end (except JPEG, whose trailer is dropped
+// on re-encoding), are rejected as polyglots; dimensions are checked from the header so
+// decompression bombs are refused before any pixel is allocated. Returns the detected format.
+func validateImage(data []byte, declaredType string) (string, error) {
+	format := detectFormat(data)
+	if format == "" {
+		return "", ErrInvalidFileType
+	}
+	if mediaType, _, err := mime.ParseMediaType(declaredType); err == nil && mediaType != "application/octet-stream" {
+		declared, ok := declaredFormats[mediaType]
+		if !ok {
+			return "", ErrInvalidFileType
+		}
+		if declared != format {
+			return "", ErrContentMismatch
+		}
+	}
+
+	if markupPattern.Match(data) {
+		return "", ErrPolyglotDetected
+	}
+	if err := checkTrailingData(data, format); err != nil {
+		return "", err
+	}
+
+	cfg, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFileType, err)
+	}
+	if decoded != format {
+		return "", ErrContentMismatch
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height); err != nil {
+		return "", err
+	}
+
+	return format, nil
+}
+
+func checkDimensions(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return ErrInvalidFileType
+	}
+	if width > maxImageDimension || height > maxImageDimension || width*height > maxImagePixels {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// checkTrailingData rejects bytes appended after the end of the image, a common way to smuggle
+// a second file into an image. JPEG is exempt: phones append their own data after the image
+// (Samsung SEFT, Motion Photo videos), the markup scan covers the trailer and re-encoding drops it.
+func checkTrailingData(data []byte, format string) error {
+	switch format {
+	case FormatPNG:
+		end := pngEnd(data)
+		if end < 0 || end != len(data) {
+			return ErrPolyglotDetected
+		}
+	case FormatGIF:
+		if _, end, err := gifFrames(data); err != nil || end != len(data) {
+			return ErrPolyglotDetected
+		}
+	case FormatWebP:
+		// RIFF size covers the whole file after the 8-byte header
+		if int(binary.LittleEndian.Uint32(data[4:8]))+8 != len(data) {
+			return ErrPolyglotDetected
+		}
+	}
+	return nil
+}
+
+// pngEnd walks the PNG chunks and returns the offset right after IEND, or -1 when malformed
+func pngEnd(data []byte) int {
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if pos+12+length > len(data) {
+			return -1
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		pos += 12 + length
+		if chunkType == "IEND" {
+			return pos
+		}
+	}
+	return -1
+}
+
+// detectVideoFormat identifies a video container from its header: WebM is an EBML document
+// with the "webm" DocType, MP4 starts with an ftyp box. Returns the content type.
+func detectVideoFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		// DocType element (0x4282) sits in the EBML header, well inside the first bytes
+		if i := bytes.Index(head, []byte{0x42, 0x82}); i > 0 && i < 64 && bytes.Contains(head[i:min(i+16, len(head))], []byte("webm")) {
+			return "video/webm"
+		}
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		if size := binary.BigEndian.Uint32(head); size >= 16 && size%4 == 0 {
+			return "video/mp4"
+		}
+	}
+	return ""
+}
+
+// validateVideo checks the beginning of an assembled video: the container must match the
+// declared type and the header must not carry markup
+func validateVideo(head []byte, declaredType string) error {
+	format := detectVideoFormat(head)
+	if format == "" {
+		return ErrInvalidFileType
+	}
+	if format != declaredType {
+		return ErrContentMismatch
+	}
+	if markupPattern.Match(head) {
+		return ErrPolyglotDetected
+	}
+	return nil
+}
+
+// validateGIF fully decodes every frame of a GIF that is stored without re-encoding.
+// Frame sizes are summed from the block structure first, so animation bombs are refused
+// before any frame is decoded.
+func validateGIF(data []byte) (image.Config, error) {
+	total, _, err := gifFrames(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if total > maxGIFPixels {
+		return image.Config{}, ErrImageTooLarge
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, fmt.Errorf("%w: %v", ErrInvalidFileType, err)
+	}
+
+	return image.Config{Width: g.Config.Width, Height: g.Config.Height}, nil
+}
+
+// gifFrames walks the GIF blocks and returns the total pixel count of all frames
+// and the offset right after the trailer
+func gifFrames(data []byte) (int, int, error) {
+	malformed := fmt.Errorf("%w: malformed GIF", ErrInvalidFileType)
+	if len(data) < 13 {
+		return 0, 0, malformed
+	}
+
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1) // Global color table
+	}
+
+	// skipSubBlocks returns the position after a sequence of data sub-blocks
+	skipSubBlocks := func(pos int) int {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos++
+			if size == 0 {
+				return pos
+			}
+			pos += size
+		}
+		return -1
+	}
+
+	total := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension: introducer, label, sub-blocks
+			pos = skipSubBlocks(pos + 2)
+		case 0x2C: // Image descriptor
+			if pos+10 > len(data) {
+				return 0, 0, malformed
+			}
+			w := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			h := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			total += w * h
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1) // Local color table
+			}
+			pos = skipSubBlocks(pos + 1) // LZW minimum code size, then image data
+		case 0x3B: // Trailer
+			return total, pos + 1, nil
+		default:
+			return 0, 0, malformed
+		}
+		if pos < 0 {
+			return 0, 0, malformed
+		}
+	}
+
+	return 0, 0, malformed
+}
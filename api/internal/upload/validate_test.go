@@ -0,0 +1,43 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestValidateImageJPEGTrailer(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	photo := buf.Bytes()
+
+	tests := []struct {
+		name    string
+		trailer string
+		wantErr error
+	}{
+		{name: "no trailer"},
+		{name: "zero padding", trailer: "\x00\x00\x00\x00"},
+		{name: "Samsung SEFT", trailer: "\x00\x00SEFH\x6a\x00\x00\x00\x01\x00\x00\x00SEFT"},
+		{name: "Motion Photo video", trailer: "\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom\x00\x00\x00\x08free"},
+		{name: "HTML", trailer: "<html><body>hi</body></html>", wantErr: ErrPolyglotDetected},
+		{name: "script", trailer: "<script>alert(1)</script>", wantErr: ErrPolyglotDetected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append(append([]byte{}, photo...), tt.trailer...)
+			format, err := validateImage(data, "image/jpeg")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && format != FormatJPEG {
+				t.Errorf("format = %q, want %q", format, FormatJPEG)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package wins
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// Analytics limits
+const (
+	DefaultAnalyticsTop = 10
+	MaxAnalyticsTop     = 50
+)
+
+// AnalyticsParams contains parameters for wins analytics
+type AnalyticsParams struct {
+	Year int // Limits months, results and tops to one year; by_year always covers all years
+	Top  int // Number of top teams and hackathons
+}
+
+// Analytics contains grouped aggregates of wins. Prizes are summed in Currency only.
+type Analytics struct {
+	Currency      string           `json:"currency"`
+	Year          int              `json:"year,omitempty"`
+	TotalWins     int              `json:"total_wins"`
+	TotalPrize    int64            `json:"total_prize"`
+	ByYear        []YearStats      `json:"by_year"`
+	ByMonth       []MonthStats     `json:"by_month"` // Wins with an award date
+	Results       []ResultStats    `json:"results"`
+	TopTeams      []TeamStats      `json:"top_teams"`
+	TopHackathons []HackathonStats `json:"top_hackathons"`
+}
+
+// YearStats are the wins of one year with the change from the previous year in percent.
+// Growth is null when the previous year had nothing to compare with.
+type YearStats struct {
+	Year        int      `json:"year"`
+	Wins        int      `json:"wins"`
+	Prize       int64    `json:"prize"`
+	WinsGrowth  *float64 `json:"wins_growth"`
+	PrizeGrowth *float64 `json:"prize_growth"`
+}
+
+// MonthStats are the wins awarded in one month
+type MonthStats struct {
+	Year  int   `json:"year"`
+	Month int   `json:"month"`
+	Wins  int   `json:"wins"`
+	Prize int64 `json:"prize"`
+}
+
+// ResultStats are the wins of one placement
+type ResultStats struct {
+	Place string `json:"place"`
+	Wins  int    `json:"wins"`
+	Prize int64  `json:"prize"`
+}
+
+// TeamStats are the wins of one team
+type TeamStats struct {
+	Team  string `json:"team"`
+	Wins  int    `json:"wins"`
+	Prize int64  `json:"prize"`
+}
+
+// HackathonStats are the wins at one hackathon
+type HackathonStats struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Wins  int    `json:"wins"`
+	Prize int64  `json:"prize"`
+}
+
+// growth returns the change from prev to cur in percent, rounded to one decimal
+func growth(cur, prev int64) *float64 {
+	if prev == 0 {
+		return nil
+	}
+	g := math.Round(float64(cur-prev)/float64(prev)*1000) / 10
+	return &g
+}
+
+// GetAnalytics returns aggregates of wins by year, month, result, team and hackathon
+func (s *Service) GetAnalytics(ctx context.Context, params AnalyticsParams) (*Analytics, error) {
+	if params.Top < 1 || params.Top > MaxAnalyticsTop {
+		params.Top = DefaultAnalyticsTop
+	}
+
+	a := &Analytics{
+		Currency:      DefaultCurrency,
+		Year:          params.Year,
+		ByYear:        []YearStats{},
+		ByMonth:       []MonthStats{},
+		Results:       []ResultStats{},
+		TopTeams:      []TeamStats{},
+		TopHackathons: []HackathonStats{},
+	}
+
+	// Prizes in other currencies are counted as wins without a prize
+	const prizeSum = "COALESCE(SUM(w.prize) FILTER (WHERE w.currency = $1), 0)"
+	where := " WHERE ($2 = 0 OR w.year = $2)"
+	args := []any{DefaultCurrency, params.Year}
+
+	err := s.db.QueryRow(ctx, "SELECT COUNT(*), "+prizeSum+" FROM wins w"+where, args...).Scan(&a.TotalWins, &a.TotalPrize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count wins: %w", err)
+	}
+
+	// By year, oldest first so that growth compares with the previous entry
+	rows, err := s.db.Query(ctx, "SELECT w.year, COUNT(*), "+prizeSum+" FROM wins w GROUP BY w.year ORDER BY w.year", DefaultCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by year: %w", err)
+	}
+	for rows.Next() {
+		var y YearStats
+		if err := rows.Scan(&y.Year, &y.Wins, &y.Prize); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan year stats: %w", err)
+		}
+		if n := len(a.ByYear); n > 0 && a.ByYear[n-1].Year == y.Year-1 {
+			prev := a.ByYear[n-1]
+			y.WinsGrowth = growth(int64(y.Wins), int64(prev.Wins))
+			y.PrizeGrowth = growth(y.Prize, prev.Prize)
+		}
+		a.ByYear = append(a.ByYear, y)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by year: %w", err)
+	}
+
+	// By month of the award date
+	rows, err = s.db.Query(ctx, `
+		SELECT EXTRACT(YEAR FROM w.award_date)::int, EXTRACT(MONTH FROM w.award_date)::int, COUNT(*), `+prizeSum+`
+		FROM wins w`+where+` AND w.award_date IS NOT NULL
+		GROUP BY 1, 2 ORDER BY 1, 2
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by month: %w", err)
+	}
+	for rows.Next() {
+		var m MonthStats
+		if err := rows.Scan(&m.Year, &m.Month, &m.Wins, &m.Prize); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan month stats: %w", err)
+		}
+		a.ByMonth = append(a.ByMonth, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by month: %w", err)
+	}
+
+	// By placement, every placement listed from best to worst; unclassified wins count as other
+	rows, err = s.db.Query(ctx, "SELECT COALESCE(w.placement, $3), COUNT(*), "+prizeSum+" FROM wins w"+where+" GROUP BY 1", DefaultCurrency, params.Year, PlaceOther)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by placement: %w", err)
+	}
+	byPlace := map[string]*ResultStats{}
+	for _, place := range Placements {
+		byPlace[place] = &ResultStats{Place: place}
+	}
+	for rows.Next() {
+		var place string
+		var wins int
+		var prize int64
+		if err := rows.Scan(&place, &wins, &prize); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan placement stats: %w", err)
+		}
+		if stats, ok := byPlace[place]; ok {
+			stats.Wins += wins
+			stats.Prize += prize
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by placement: %w", err)
+	}
+	for _, place := range Placements {
+		a.Results = append(a.Results, *byPlace[place])
+	}
+
+	// Top teams
+	rows, err = s.db.Query(ctx, `
+		SELECT MIN(w.team_name), COUNT(*), `+prizeSum+`
+		FROM wins w`+where+`
+		GROUP BY LOWER(w.team_name)
+		ORDER BY 2 DESC, 3 DESC, 1
+		LIMIT $3
+	`, DefaultCurrency, params.Year, params.Top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by team: %w", err)
+	}
+	for rows.Next() {
+		var t TeamStats
+		if err := rows.Scan(&t.Team, &t.Wins, &t.Prize); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan team stats: %w", err)
+		}
+		a.TopTeams = append(a.TopTeams, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by team: %w", err)
+	}
+
+	// Top hackathons
+	rows, err = s.db.Query(ctx, `
+		SELECT h.id, h.name, COUNT(*), `+prizeSum+`
+		FROM `+winTables+where+`
+		GROUP BY h.id, h.name
+		ORDER BY 3 DESC, 4 DESC, 2
+		LIMIT $3
+	`, DefaultCurrency, params.Year, params.Top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by hackathon: %w", err)
+	}
+	for rows.Next() {
+		var h HackathonStats
+		if err := rows.Scan(&h.ID, &h.Name, &h.Wins, &h.Prize); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan hackathon stats: %w", err)
+		}
+		a.TopHackathons = append(a.TopHackathons, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate wins by hackathon: %w", err)
+	}
+
+	return a, nil
+}
+
+// GetAnalytics handles GET /api/wins/analytics?year=&top=
+func (h *Handler) GetAnalytics(w http.ResponseWriter, r *http.Request) {
+	var params AnalyticsParams
+	if year, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil {
+		params.Year = year
+	}
+	if top, err := strconv.Atoi(r.URL.Query().Get("top")); err == nil {
+		params.Top = top
+	}
+
+	h.writeAnalytics(w, r, params)
+}
+
+// GetPublicAnalytics handles GET /api/public/wins/analytics (all years, default top)
+func (h *Handler) GetPublicAnalytics(w http.ResponseWriter, r *http.Request) {
+	h.writeAnalytics(w, r, AnalyticsParams{})
+}
+
+func (h *Handler) writeAnalytics(w http.ResponseWriter, r *http.Request, params AnalyticsParams) {
+	analytics, err := h.service.GetAnalytics(r.Context(), params)
+	if err != nil {
+		slog.Error("failed to get wins analytics", "error", err)
+		response.InternalError(w, "failed to get analytics")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, analytics)
+}
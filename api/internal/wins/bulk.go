@@ -0,0 +1,249 @@
+package wins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itam-misis/itam-api/internal/audit"
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// Bulk actions
+const (
+	BulkUpdate  = "update"  // Set the same fields on every win
+	BulkDelete  = "delete"  // Delete the wins
+	BulkReorder = "reorder" // Permute the sort_order of the wins so that they are listed in the given order
+)
+
+// MaxBulkIDs limits the wins changed by one bulk request
+const MaxBulkIDs = 500
+
+// BulkRequest is the request body of POST /api/wins/bulk
+type BulkRequest struct {
+	Action string            `json:"action"`
+	IDs    []int64           `json:"ids"`    // For reorder in display order, top first
+	Update *UpdateWinRequest `json:"update"` // Fields to set, for update
+}
+
+// Validate checks the action and removes repeated IDs, keeping the first occurrence
+func (r *BulkRequest) Validate() error {
+	switch r.Action {
+	case BulkUpdate:
+		if r.Update == nil {
+			return ErrBulkNoChanges
+		}
+		if err := r.Update.Validate(); err != nil {
+			return err
+		}
+	case BulkDelete, BulkReorder:
+	default:
+		return ErrInvalidBulkAction
+	}
+
+	if len(r.IDs) == 0 {
+		return ErrBulkIDsRequired
+	}
+	if len(r.IDs) > MaxBulkIDs {
+		return ErrBulkTooMany
+	}
+
+	seen := make(map[int64]bool, len(r.IDs))
+	ids := r.IDs[:0]
+	for _, id := range r.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	r.IDs = ids
+	return nil
+}
+
+// BulkResult is the outcome of a bulk request
+type BulkResult struct {
+	Action string  `json:"action"`
+	Count  int     `json:"count"`
+	IDs    []int64 `json:"ids"`
+}
+
+// Bulk applies an update, delete or reorder to several wins in one transaction.
+// Nothing is written if any of the wins does not exist; a single audit entry summarizes the change.
+func (s *Service) Bulk(ctx context.Context, req *BulkRequest, userID int64, ipAddress string) (*BulkResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the wins and keep them for the audit log
+	rows, err := tx.Query(ctx, `
+		SELECT `+winColumns("")+`
+		FROM `+winTables+`
+		WHERE w.id = ANY($1)
+		FOR UPDATE OF w
+	`, req.IDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wins: %w", err)
+	}
+	existing := make(map[int64]Win, len(req.IDs))
+	for rows.Next() {
+		var w Win
+		if err := scanWin(rows, &w); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan win: %w", err)
+		}
+		existing[w.ID] = w
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query wins: %w", err)
+	}
+
+	var missing []string
+	for _, id := range req.IDs {
+		if _, ok := existing[id]; !ok {
+			missing = append(missing, strconv.FormatInt(id, 10))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrWinNotFound, strings.Join(missing, ", "))
+	}
+
+	bulkLog := map[string]any{
+		"action": "bulk_" + req.Action,
+		"ids":    req.IDs,
+		"count":  len(req.IDs),
+	}
+	auditAction := audit.ActionUpdate
+
+	switch req.Action {
+	case BulkUpdate:
+		c, err := loadClassifier(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range req.IDs {
+			changed, err := applyUpdate(ctx, tx, c, id, req.Update)
+			if err != nil {
+				return nil, fmt.Errorf("win %d: %w", id, err)
+			}
+			if !changed {
+				return nil, ErrBulkNoChanges
+			}
+		}
+		bulkLog["changes"] = req.Update
+
+	case BulkDelete:
+		if _, err := tx.Exec(ctx, "DELETE FROM wins WHERE id = ANY($1)", req.IDs); err != nil {
+			return nil, fmt.Errorf("failed to delete wins: %w", err)
+		}
+		deleted := make([]Win, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			deleted = append(deleted, existing[id])
+		}
+		bulkLog["deleted"] = deleted
+		auditAction = audit.ActionDelete
+
+	case BulkReorder:
+		before := make(map[int64]int, len(req.IDs))
+		for _, id := range req.IDs {
+			before[id] = existing[id].SortOrder
+		}
+		orders := reorderedSortOrders(req.IDs, before)
+		for i, id := range req.IDs {
+			if _, err := tx.Exec(ctx, "UPDATE wins SET sort_order = $1 WHERE id = $2", orders[i], id); err != nil {
+				return nil, fmt.Errorf("failed to reorder wins: %w", err)
+			}
+		}
+		bulkLog["before"] = before
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, auditAction, audit.EntityWin, nil, bulkLog, ipAddress)
+
+	return &BulkResult{Action: req.Action, Count: len(req.IDs), IDs: req.IDs}, nil
+}
+
+// reorderedSortOrders hands the existing sort_order values of the wins out again in the order of ids
+// (lists show higher sort_order first), so the wins keep their slots among the others.
+// Equal values are lowered just enough to make the order strict.
+func reorderedSortOrders(ids []int64, current map[int64]int) []int {
+	orders := make([]int, 0, len(ids))
+	for _, id := range ids {
+		orders = append(orders, current[id])
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(orders)))
+	for i := 1; i < len(orders); i++ {
+		if orders[i] >= orders[i-1] {
+			orders[i] = orders[i-1] - 1
+		}
+	}
+	return orders
+}
+
+// Bulk handles POST /api/wins/bulk
+func (h *Handler) Bulk(w http.ResponseWriter, r *http.Request) {
+	var req BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	ipAddress := r.RemoteAddr
+
+	result, err := h.service.Bulk(r.Context(), &req, userID, ipAddress)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidBulkAction):
+			response.ValidationError(w, "action must be update, delete or reorder")
+		case errors.Is(err, ErrBulkIDsRequired):
+			response.ValidationError(w, "ids are required")
+		case errors.Is(err, ErrBulkTooMany):
+			response.ValidationError(w, fmt.Sprintf("at most %d ids per request", MaxBulkIDs))
+		case errors.Is(err, ErrBulkNoChanges):
+			response.ValidationError(w, "update must set at least one field")
+		case errors.Is(err, ErrWinNotFound):
+			response.NotFound(w, err.Error())
+		case errors.Is(err, ErrTeamNameRequired):
+			response.ValidationError(w, "team name is required")
+		case errors.Is(err, ErrHackathonRequired):
+			response.ValidationError(w, "hackathon_id or hackathon name is required")
+		case errors.Is(err, ErrResultRequired):
+			response.ValidationError(w, "result is required")
+		case errors.Is(err, ErrInvalidYear):
+			response.ValidationError(w, "year must be between 2000 and 2100")
+		case errors.Is(err, ErrInvalidCurrency):
+			response.ValidationError(w, "currency must be a 3-letter ISO 4217 code")
+		case errors.Is(err, ErrInvalidPlacement):
+			response.ValidationError(w, "placement must be one of first, second, third, place, prizewinner, special, finalist, other")
+		case errors.Is(err, ErrInvalidRank):
+			response.ValidationError(w, err.Error())
+		case errors.Is(err, ErrHackathonNotFound):
+			response.ValidationError(w, "hackathon not found")
+		case errors.Is(err, ErrMemberNotFound):
+			response.ValidationError(w, "participant not found")
+		default:
+			slog.Error("failed to apply bulk wins action", "action", req.Action, "error", err)
+			response.InternalError(w, "failed to apply bulk action")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
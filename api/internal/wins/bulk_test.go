@@ -0,0 +1,48 @@
+package wins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReorderedSortOrders(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []int64
+		current map[int64]int
+		want    []int
+	}{
+		{
+			name:    "existing slots are permuted",
+			ids:     []int64{3, 1, 2},
+			current: map[int64]int{1: 50, 2: 10, 3: 30},
+			want:    []int{50, 30, 10},
+		},
+		{
+			name:    "already in order",
+			ids:     []int64{1, 2},
+			current: map[int64]int{1: 7, 2: 4},
+			want:    []int{7, 4},
+		},
+		{
+			name:    "equal values are made strict",
+			ids:     []int64{2, 1, 3},
+			current: map[int64]int{1: 0, 2: 0, 3: 0},
+			want:    []int{0, -1, -2},
+		},
+		{
+			name:    "a tie below a gap",
+			ids:     []int64{1, 2, 3, 4},
+			current: map[int64]int{1: 5, 2: 5, 3: 5, 4: 1},
+			want:    []int{5, 4, 3, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reorderedSortOrders(tt.ids, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package wins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Import modes for rows that match an existing win
+const (
+	ImportModeSkip   = "skip"   // Keep the existing win
+	ImportModeUpdate = "update" // Overwrite the existing win with the row
+	ImportModeInsert = "insert" // Insert anyway (previous behaviour)
+)
+
+// Row outcomes reported by the import
+const (
+	RowInserted = "inserted"
+	RowUpdated  = "updated"
+	RowSkipped  = "skipped"
+	RowError    = "error"
+)
+
+// Match kinds
+const (
+	MatchExact = "exact"
+	MatchFuzzy = "fuzzy"
+)
+
+// minSimilarity is the similarity every field needs for a fuzzy match
+const minSimilarity = 0.85
+
+// ValidImportMode reports whether mode is a known import mode
+func ValidImportMode(mode string) bool {
+	switch mode {
+	case ImportModeSkip, ImportModeUpdate, ImportModeInsert:
+		return true
+	}
+	return false
+}
+
+// normalizeText lowercases a value and drops quotes, punctuation and repeated whitespace,
+// so «Цифровой прорыв» and "цифровой  прорыв" compare equal
+func normalizeText(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if r == 'ё' {
+			r = 'е'
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+			continue
+		}
+		space = true
+	}
+	return b.String()
+}
+
+// winKey is the normalized identity of a win: team + hackathon + year + result
+type winKey struct {
+	Team      string
+	Hackathon string
+	Year      int
+	Result    string
+}
+
+func newWinKey(team, hackathon string, year int, result string) winKey {
+	return winKey{
+		Team:      normalizeText(team),
+		Hackathon: normalizeText(hackathon),
+		Year:      year,
+		Result:    normalizeText(result),
+	}
+}
+
+// winMatcher finds existing wins for imported rows
+type winMatcher struct {
+	exact  map[winKey]int64
+	byYear map[int][]matchCandidate
+}
+
+type matchCandidate struct {
+	key winKey
+	id  int64
+}
+
+// loadMatcher indexes every existing win
+func loadMatcher(ctx context.Context, q querier) (*winMatcher, error) {
+	rows, err := q.Query(ctx, "SELECT w.id, w.team_name, h.name, w.year, w.result FROM "+winTables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wins: %w", err)
+	}
+	defer rows.Close()
+
+	m := &winMatcher{exact: map[winKey]int64{}, byYear: map[int][]matchCandidate{}}
+	for rows.Next() {
+		var id int64
+		var team, hackathon, result string
+		var year int
+		if err := rows.Scan(&id, &team, &hackathon, &year, &result); err != nil {
+			return nil, err
+		}
+		m.add(newWinKey(team, hackathon, year, result), id)
+	}
+	return m, rows.Err()
+}
+
+func (m *winMatcher) add(key winKey, id int64) {
+	if _, ok := m.exact[key]; ok {
+		return
+	}
+	m.exact[key] = id
+	m.byYear[key.Year] = append(m.byYear[key.Year], matchCandidate{key: key, id: id})
+}
+
+// find returns the existing win for key and how it matched, or 0.
+// The year must be equal; team, hackathon and result may differ by typos.
+func (m *winMatcher) find(key winKey) (int64, string) {
+	if id, ok := m.exact[key]; ok {
+		return id, MatchExact
+	}
+
+	var bestID int64
+	best := 0.0
+	for _, c := range m.byYear[key.Year] {
+		score, ok := fuzzyMatch(key, c.key)
+		if ok && score > best {
+			best, bestID = score, c.id
+		}
+	}
+	if bestID == 0 {
+		return 0, ""
+	}
+	return bestID, MatchFuzzy
+}
+
+// fuzzyMatch compares two keys field by field and returns their combined similarity
+func fuzzyMatch(a, b winKey) (float64, bool) {
+	total := 0.0
+	for _, pair := range [][2]string{{a.Team, b.Team}, {a.Hackathon, b.Hackathon}, {a.Result, b.Result}} {
+		sim := similarity(pair[0], pair[1])
+		if sim < minSimilarity {
+			return 0, false
+		}
+		total += sim
+	}
+	return total, true
+}
+
+// similarity is 1 minus the edit distance relative to the longer string.
+// Numbers must be equal: "1 место" and "2 место" or "Хакатон 2023" and "Хакатон 2024" never match.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if digits(a) != digits(b) {
+		return 0
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// digits returns the numbers in s separated by spaces
+func digits(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }), " ")
+}
+
+// levenshtein returns the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,271 @@
+package wins
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// exportContentTypes maps export formats to their content types
+var exportContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatJSON: "application/json",
+}
+
+// exportSheet is the sheet name of XLSX exports
+const exportSheet = "Победы"
+
+// ExportRow is an exported win with the fields the import reads
+type ExportRow struct {
+	TeamName      string  `json:"team_name"`
+	HackathonName string  `json:"hackathon_name"`
+	Result        string  `json:"result"`
+	Prize         int     `json:"prize"`
+	AwardDate     *string `json:"award_date"` // YYYY-MM-DD
+	Year          int     `json:"year"`
+	Link          *string `json:"link"`
+	Currency      string  `json:"currency"`
+
+	awardDate *time.Time
+}
+
+// Export writes all wins matching the filters of params to w in the layout Import reads back:
+// CSV with the Wins.csv header, XLSX with the same header, or a JSON array.
+// Nothing is written if the query fails; errors while streaming leave a truncated file.
+func (s *Service) Export(ctx context.Context, w io.Writer, params ListWinsParams, format string) error {
+	baseQuery, args := filterQuery(params)
+	rows, err := s.db.Query(ctx, `
+		SELECT w.team_name, h.name, w.result, w.prize, w.award_date, w.year, w.link, w.currency
+		`+baseQuery+`
+		ORDER BY `+orderBy(params.Sort)+`
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query wins: %w", err)
+	}
+	defer rows.Close()
+
+	switch format {
+	case FormatCSV:
+		err = exportCSV(w, rows)
+	case FormatXLSX:
+		err = exportXLSX(w, rows)
+	case FormatJSON:
+		err = exportJSON(w, rows)
+	default:
+		err = fmt.Errorf("unsupported export format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+func scanExportRow(rows pgx.Rows) (*ExportRow, error) {
+	var row ExportRow
+	if err := rows.Scan(
+		&row.TeamName, &row.HackathonName, &row.Result, &row.Prize, &row.awardDate, &row.Year, &row.Link, &row.Currency,
+	); err != nil {
+		return nil, fmt.Errorf("failed to scan win: %w", err)
+	}
+	if row.awardDate != nil {
+		date := row.awardDate.Format("2006-01-02")
+		row.AwardDate = &date
+	}
+	return &row, nil
+}
+
+// exportCSV writes ;-separated UTF-8 with a byte order mark, so that Excel detects the encoding
+func exportCSV(w io.Writer, rows pgx.Rows) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = ';'
+
+	if err := csvWriter.Write(expectedHeaders); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		row, err := scanExportRow(rows)
+		if err != nil {
+			return err
+		}
+
+		var awardDate, link string
+		if row.awardDate != nil {
+			awardDate = row.awardDate.Format("02.01.2006")
+		}
+		if row.Link != nil {
+			link = *row.Link
+		}
+
+		record := []string{
+			row.TeamName, row.HackathonName, row.Result, strconv.Itoa(row.Prize),
+			awardDate, strconv.Itoa(row.Year), link, row.Currency,
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// exportXLSX writes a single sheet with real number and date cells
+func exportXLSX(w io.Writer, rows pgx.Rows) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", exportSheet); err != nil {
+		return err
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	dateFormat := "dd.mm.yyyy"
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+
+	sw, err := f.NewStreamWriter(exportSheet)
+	if err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(1, 3, 30); err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(4, len(expectedHeaders), 16); err != nil {
+		return err
+	}
+
+	header := make([]any, len(expectedHeaders))
+	for i, name := range expectedHeaders {
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: name}
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	for rowNum := 2; rows.Next(); rowNum++ {
+		row, err := scanExportRow(rows)
+		if err != nil {
+			return err
+		}
+
+		var awardDate, link any
+		if row.awardDate != nil {
+			awardDate = excelize.Cell{StyleID: dateStyle, Value: *row.awardDate}
+		}
+		if row.Link != nil {
+			link = *row.Link
+		}
+
+		cell, err := excelize.CoordinatesToCellName(1, rowNum)
+		if err != nil {
+			return err
+		}
+		values := []any{row.TeamName, row.HackathonName, row.Result, row.Prize, awardDate, row.Year, link, row.Currency}
+		if err := sw.SetRow(cell, values); err != nil {
+			return err
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// exportJSON streams a JSON array of ExportRow
+func exportJSON(w io.Writer, rows pgx.Rows) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	for rows.Next() {
+		row, err := scanExportRow(rows)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if !first {
+			data = append([]byte{','}, data...)
+		}
+		first = false
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// Export handles GET /api/wins/export?format=csv|xlsx|json with the filters of List
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatCSV
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		response.ValidationError(w, "format must be csv, xlsx or json")
+		return
+	}
+
+	params, err := listParams(r)
+	if err != nil {
+		response.ValidationError(w, err.Error())
+		return
+	}
+
+	filename := "wins"
+	if params.Year != 0 {
+		filename += "-" + strconv.Itoa(params.Year)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+
+	out := &exportWriter{w: w}
+	if err := h.service.Export(r.Context(), out, params, format); err != nil {
+		slog.Error("failed to export wins", "error", err)
+		// Once streaming has started the client gets a truncated file
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			response.InternalError(w, "failed to export wins")
+		}
+	}
+}
+
+// exportWriter records whether any part of the export was sent
+type exportWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	e.written = true
+	return e.w.Write(p)
+}
@@ -0,0 +1,339 @@
+package wins
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Import formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatJSON = "json"
+)
+
+// Import fields
+const (
+	fieldTeamName      = "team_name"
+	fieldHackathonName = "hackathon_name"
+	fieldResult        = "result"
+	fieldPrize         = "prize"
+	fieldAwardDate     = "award_date"
+	fieldYear          = "year"
+	fieldLink          = "link"
+	fieldCurrency      = "currency"
+)
+
+// expectedHeaders is the column layout of Wins.csv, used when the header row is not recognized
+var expectedHeaders = []string{"Название команды", "Название хакатона", "Результат", "Призовой", "Дата награждения", "Год", "Ссылка", "Валюта"}
+
+// columnAliases maps header names, in Russian and English, to fields.
+// Headers are compared after normalizeText, so case, quotes and underscores do not matter.
+var columnAliases = map[string][]string{
+	fieldTeamName:      {"Название команды", "Команда", "Team", "Team name"},
+	fieldHackathonName: {"Название хакатона", "Хакатон", "Мероприятие", "Hackathon", "Hackathon name", "Event"},
+	fieldResult:        {"Результат", "Место", "Result", "Place"},
+	fieldPrize:         {"Призовой", "Призовые", "Приз", "Сумма", "Prize", "Prize amount"},
+	fieldAwardDate:     {"Дата награждения", "Дата", "Award date", "Date"},
+	fieldYear:          {"Год", "Year"},
+	fieldLink:          {"Ссылка", "Link", "URL"},
+	fieldCurrency:      {"Валюта", "Currency"},
+}
+
+// requiredColumns must be present in a mapped header
+var requiredColumns = []string{fieldTeamName, fieldHackathonName, fieldResult, fieldYear}
+
+// legacyColumns is the field order of expectedHeaders
+var legacyColumns = []string{
+	fieldTeamName, fieldHackathonName, fieldResult, fieldPrize, fieldAwardDate, fieldYear, fieldLink, fieldCurrency,
+}
+
+// headerFields maps normalized header names to fields
+var headerFields = func() map[string]string {
+	m := map[string]string{}
+	for field, aliases := range columnAliases {
+		m[normalizeText(field)] = field
+		for _, alias := range aliases {
+			m[normalizeText(alias)] = field
+		}
+	}
+	return m
+}()
+
+// delimiters are the CSV separators tried by detectDelimiter, the first one wins ties
+var delimiters = []rune{';', ',', '\t', '|'}
+
+// sourceRow is a row of any import format, keyed by field
+type sourceRow struct {
+	Row    int
+	Fields map[string]string
+	Err    error
+}
+
+// detectFormat guesses the format of an uploaded file from its content
+func detectFormat(data []byte) string {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatXLSX
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// readRows parses an uploaded file in the given format, or a detected one if empty
+func readRows(data []byte, format string) ([]sourceRow, error) {
+	if format == "" {
+		format = detectFormat(data)
+	}
+	switch format {
+	case FormatCSV:
+		return readCSV(data)
+	case FormatXLSX:
+		return readXLSX(data)
+	case FormatJSON:
+		return readJSON(data)
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+// mapColumns resolves the column index of each field from a header row.
+// A header without any known name falls back to the Wins.csv layout.
+func mapColumns(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, name := range header {
+		field, ok := headerFields[normalizeText(name)]
+		if !ok {
+			continue
+		}
+		if _, dup := columns[field]; dup {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[field] = i
+	}
+
+	if len(columns) == 0 {
+		if len(header) < 6 {
+			return nil, fmt.Errorf("unknown columns, expected %s", strings.Join(expectedHeaders, ", "))
+		}
+		for i, field := range legacyColumns {
+			columns[field] = i
+		}
+		return columns, nil
+	}
+
+	var missing []string
+	for _, field := range requiredColumns {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, columnAliases[field][0])
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing columns: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// mapRecord picks the fields of a record by column index
+func mapRecord(columns map[string]int, record []string) map[string]string {
+	fields := make(map[string]string, len(columns))
+	for field, i := range columns {
+		if i < len(record) {
+			fields[field] = strings.TrimSpace(record[i])
+		}
+	}
+	return fields
+}
+
+// decodeText converts CSV data to UTF-8. Excel saves CSV as Windows-1251 or,
+// for "Unicode text", as UTF-16 with a byte order mark.
+func decodeText(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		return data[3:], nil
+	case bytes.HasPrefix(data, []byte("\xff\xfe")), bytes.HasPrefix(data, []byte("\xfe\xff")):
+		decoder := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()
+		return decoder.Bytes(data)
+	case utf8.Valid(data):
+		return data, nil
+	}
+	return charmap.Windows1251.NewDecoder().Bytes(data)
+}
+
+// detectDelimiter picks the separator occurring most often outside quotes in the header line
+func detectDelimiter(data []byte) rune {
+	counts := map[rune]int{}
+	quoted := false
+	for _, r := range string(data) {
+		if r == '"' {
+			quoted = !quoted
+			continue
+		}
+		if !quoted && (r == '\n' || r == '\r') {
+			break
+		}
+		if !quoted {
+			counts[r]++
+		}
+	}
+
+	best := delimiters[0]
+	for _, d := range delimiters[1:] {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
+
+// readCSV reads CSV of any common delimiter and encoding with a header row
+func readCSV(data []byte) ([]sourceRow, error) {
+	data, err := decodeText(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode CSV: %w", err)
+	}
+
+	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader.Comma = detectDelimiter(data)
+	csvReader.LazyQuotes = true
+	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns, err := mapColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sourceRow
+	rowNum := 1 // Start from 1 (after header)
+
+	for {
+		rowNum++
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rows = append(rows, sourceRow{Row: rowNum, Err: fmt.Errorf("failed to read row: %w", err)})
+			continue
+		}
+		rows = append(rows, sourceRow{Row: rowNum, Fields: mapRecord(columns, record)})
+	}
+
+	return rows, nil
+}
+
+// readXLSX reads the first sheet of a workbook with a header row.
+// Cells are read unformatted so that dates and prizes do not depend on the cell format.
+func readXLSX(data []byte) ([]sourceRow, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+	records, err := f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet: %w", err)
+	}
+
+	// Leading empty rows are skipped
+	start := 0
+	for start < len(records) && isEmptyRecord(records[start]) {
+		start++
+	}
+	if start == len(records) {
+		return nil, errors.New("sheet is empty")
+	}
+
+	columns, err := mapColumns(records[start])
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sourceRow
+	for i := start + 1; i < len(records); i++ {
+		if isEmptyRecord(records[i]) {
+			continue
+		}
+		fields := mapRecord(columns, records[i])
+		// Dates are stored as serial numbers
+		if serial, err := strconv.ParseFloat(fields[fieldAwardDate], 64); err == nil {
+			if date, err := excelize.ExcelDateToTime(serial, false); err == nil {
+				fields[fieldAwardDate] = date.Format("2006-01-02")
+			}
+		}
+		rows = append(rows, sourceRow{Row: i + 1, Fields: fields})
+	}
+
+	return rows, nil
+}
+
+func isEmptyRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// readJSON reads an array of objects keyed by field names or any header alias.
+// Rows are numbered from 1 by their position in the array.
+func readJSON(data []byte) ([]sourceRow, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &items); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	rows := make([]sourceRow, 0, len(items))
+	for i, item := range items {
+		row := sourceRow{Row: i + 1, Fields: map[string]string{}}
+
+		var object map[string]any
+		if err := json.Unmarshal(item, &object); err != nil {
+			row.Err = errors.New("row must be an object")
+			rows = append(rows, row)
+			continue
+		}
+
+		for key, value := range object {
+			field, ok := headerFields[normalizeText(key)]
+			if !ok {
+				continue
+			}
+			switch v := value.(type) {
+			case nil:
+			case string:
+				row.Fields[field] = strings.TrimSpace(v)
+			case float64:
+				row.Fields[field] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				row.Err = fmt.Errorf("%s must be a string or a number", key)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -3,9 +3,13 @@ package wins
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 
@@ -25,8 +29,10 @@ func NewHandler(service *Service) *Handler {
 
 // List handles GET /api/wins
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	params := ListWinsParams{
-		Search: r.URL.Query().Get("search"),
+	params, err := listParams(r)
+	if err != nil {
+		response.ValidationError(w, err.Error())
+		return
 	}
 
 	if page := r.URL.Query().Get("page"); page != "" {
@@ -47,12 +53,6 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		params.PageSize = 20
 	}
 
-	if year := r.URL.Query().Get("year"); year != "" {
-		if y, err := strconv.Atoi(year); err == nil {
-			params.Year = y
-		}
-	}
-
 	result, err := h.service.List(r.Context(), params)
 	if err != nil {
 		slog.Error("failed to list wins", "error", err)
@@ -63,16 +63,53 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, result)
 }
 
-// ListPublic handles GET /api/public/wins
-func (h *Handler) ListPublic(w http.ResponseWriter, r *http.Request) {
-	wins, err := h.service.ListPublic(r.Context())
-	if err != nil {
-		slog.Error("failed to list public wins", "error", err)
-		response.InternalError(w, "failed to list wins")
-		return
+// listParams parses the search, filter and sort query parameters shared by List and Export
+func listParams(r *http.Request) (ListWinsParams, error) {
+	params := ListWinsParams{
+		Search: strings.Join(strings.Fields(r.URL.Query().Get("search")), " "),
+	}
+
+	if year := r.URL.Query().Get("year"); year != "" {
+		y, err := strconv.Atoi(year)
+		if err != nil || y < 2000 || y > 2100 {
+			return params, errors.New("year must be between 2000 and 2100")
+		}
+		params.Year = y
+	}
+
+	if hackathonID := r.URL.Query().Get("hackathon_id"); hackathonID != "" {
+		id, err := strconv.ParseInt(hackathonID, 10, 64)
+		if err != nil || id < 1 {
+			return params, errors.New("hackathon_id must be a positive integer")
+		}
+		params.HackathonID = id
 	}
 
-	response.JSON(w, http.StatusOK, wins)
+	err := placementParams(r, &params)
+	return params, err
+}
+
+// placementParams parses ?placement=first,second (deduplicated and sorted) and ?sort=date|placement|prize
+func placementParams(r *http.Request, params *ListWinsParams) error {
+	if placement := r.URL.Query().Get("placement"); placement != "" {
+		for _, p := range strings.Split(placement, ",") {
+			p = strings.TrimSpace(p)
+			if !ValidPlacement(p) {
+				return fmt.Errorf("unknown placement %q", p)
+			}
+			if !slices.Contains(params.Placements, p) {
+				params.Placements = append(params.Placements, p)
+			}
+		}
+		slices.Sort(params.Placements)
+	}
+
+	params.Sort = r.URL.Query().Get("sort")
+	if _, ok := sortKeys[params.Sort]; params.Sort != "" && !ok {
+		return errors.New("sort must be date, placement or prize")
+	}
+
+	return nil
 }
 
 // Get handles GET /api/wins/:id
@@ -114,13 +151,23 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, ErrTeamNameRequired):
 			response.ValidationError(w, "team name is required")
 		case errors.Is(err, ErrHackathonRequired):
-			response.ValidationError(w, "hackathon name is required")
+			response.ValidationError(w, "hackathon_id or hackathon name is required")
 		case errors.Is(err, ErrResultRequired):
 			response.ValidationError(w, "result is required")
 		case errors.Is(err, ErrYearRequired):
 			response.ValidationError(w, "year is required")
 		case errors.Is(err, ErrInvalidYear):
 			response.ValidationError(w, "year must be between 2000 and 2100")
+		case errors.Is(err, ErrInvalidCurrency):
+			response.ValidationError(w, "currency must be a 3-letter ISO 4217 code")
+		case errors.Is(err, ErrInvalidPlacement):
+			response.ValidationError(w, "placement must be one of first, second, third, place, prizewinner, special, finalist, other")
+		case errors.Is(err, ErrInvalidRank):
+			response.ValidationError(w, err.Error())
+		case errors.Is(err, ErrHackathonNotFound):
+			response.ValidationError(w, "hackathon not found")
+		case errors.Is(err, ErrMemberNotFound):
+			response.ValidationError(w, "participant not found")
 		default:
 			slog.Error("failed to create win", "error", err)
 			response.InternalError(w, "failed to create win")
@@ -156,11 +203,21 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, ErrTeamNameRequired):
 			response.ValidationError(w, "team name is required")
 		case errors.Is(err, ErrHackathonRequired):
-			response.ValidationError(w, "hackathon name is required")
+			response.ValidationError(w, "hackathon_id or hackathon name is required")
 		case errors.Is(err, ErrResultRequired):
 			response.ValidationError(w, "result is required")
 		case errors.Is(err, ErrInvalidYear):
 			response.ValidationError(w, "year must be between 2000 and 2100")
+		case errors.Is(err, ErrInvalidCurrency):
+			response.ValidationError(w, "currency must be a 3-letter ISO 4217 code")
+		case errors.Is(err, ErrInvalidPlacement):
+			response.ValidationError(w, "placement must be one of first, second, third, place, prizewinner, special, finalist, other")
+		case errors.Is(err, ErrInvalidRank):
+			response.ValidationError(w, err.Error())
+		case errors.Is(err, ErrHackathonNotFound):
+			response.ValidationError(w, "hackathon not found")
+		case errors.Is(err, ErrMemberNotFound):
+			response.ValidationError(w, "participant not found")
 		default:
 			slog.Error("failed to update win", "id", id, "error", err)
 			response.InternalError(w, "failed to update win")
@@ -198,7 +255,8 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Import handles POST /api/wins/import
+// Import handles POST /api/wins/import (CSV, XLSX or JSON file)
+// With dry_run=true nothing is written; the returned preview_id is committed via POST /api/wins/import/:id/commit.
 func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form
 	err := r.ParseMultipartForm(10 << 20) // 10MB max
@@ -208,26 +266,59 @@ func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get file
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
 		response.BadRequest(w, "file is required")
 		return
 	}
 	defer file.Close()
 
+	// Rows matching an existing win: skip (default), update or insert
+	opts := ImportOptions{
+		Mode:   r.FormValue("mode"),
+		Format: importFormat(header.Filename),
+		DryRun: r.FormValue("dry_run") == "true",
+	}
+	if opts.Mode != "" && !ValidImportMode(opts.Mode) {
+		response.ValidationError(w, "mode must be skip, update or insert")
+		return
+	}
+
 	userID, _ := auth.GetUserIDFromContext(r.Context())
 	ipAddress := r.RemoteAddr
 
-	result, err := h.service.ImportCSV(r.Context(), file, userID, ipAddress)
+	result, err := h.service.Import(r.Context(), file, opts, userID, ipAddress)
 	if err != nil {
-		slog.Error("failed to import wins", "error", err)
-		response.BadRequest(w, err.Error())
+		switch {
+		case errors.Is(err, ErrInvalidImportFile):
+			response.BadRequest(w, err.Error())
+		case errors.Is(err, ErrInvalidImportMode):
+			response.ValidationError(w, "mode must be skip, update or insert")
+		case errors.Is(err, ErrPreviewStale):
+			response.Conflict(w, err.Error()+", import the file again")
+		default:
+			slog.Error("failed to import wins", "error", err)
+			response.InternalError(w, "failed to import wins")
+		}
 		return
 	}
 
 	response.JSON(w, http.StatusOK, result)
 }
 
+// importFormat returns the import format for a file extension, or "" to detect it
+func importFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	case ".json":
+		return FormatJSON
+	}
+	return ""
+}
+
 // GetYears handles GET /api/wins/years
 func (h *Handler) GetYears(w http.ResponseWriter, r *http.Request) {
 	years, err := h.service.GetYears(r.Context())
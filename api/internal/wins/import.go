@@ -2,72 +2,110 @@ package wins
 
 import (
 	"context"
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/itam-misis/itam-api/internal/audit"
 )
 
-// ImportCSV imports wins from CSV data
-// CSV format: Название команды;Название хакатона;Результат;Призовой;Дата награждения;Год;Ссылка
-// Delimiter: ;
-func (s *Service) ImportCSV(ctx context.Context, reader io.Reader, userID int64, ipAddress string) (*ImportResult, error) {
-	csvReader := csv.NewReader(reader)
-	csvReader.Comma = ';'
-	csvReader.LazyQuotes = true
-	csvReader.TrimLeadingSpace = true
+// Import imports wins from a CSV, XLSX or JSON file.
+// CSV and XLSX need a header row; columns are found by name, in Russian or English, in any order:
+// Название команды;Название хакатона;Результат;Призовой;Дата награждения;Год;Ссылка;Валюта
+// CSV delimiter (; , tab or |) and encoding (UTF-8, UTF-16 or Windows-1251) are detected.
+// JSON is an array of objects with the same names or the field names (team_name, hackathon_name, ...).
+// Hackathons are matched by name (case-insensitive) or created. Currency is optional, RUB by default.
+// Rows matching an existing win (team + hackathon + year + result, tolerating typos and quote styles)
+// are skipped, update the win or are inserted anyway, depending on the mode.
+// Invalid rows are reported and left out; all other rows are written in one transaction.
+// A dry run writes nothing and stores the plan as a preview for CommitImport.
+func (s *Service) Import(ctx context.Context, reader io.Reader, opts ImportOptions, userID int64, ipAddress string) (*ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = ImportModeSkip
+	}
+	if !ValidImportMode(opts.Mode) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidImportMode, opts.Mode)
+	}
 
-	result := &ImportResult{
-		Errors: []ImportError{},
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	rows, err := readRows(data, opts.Format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
 	}
 
-	// Skip header row
-	header, err := csvReader.Read()
+	plan, result, err := s.planImport(ctx, rows, opts.Mode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		return nil, err
 	}
 
-	// Validate header (optional, just log)
-	expectedHeaders := []string{"Название команды", "Название хакатона", "Результат", "Призовой", "Дата награждения", "Год", "Ссылка"}
-	if len(header) < 6 {
-		return nil, fmt.Errorf("CSV must have at least 6 columns, got %d", len(header))
+	if opts.DryRun {
+		if err := s.savePreview(ctx, plan, result, userID); err != nil {
+			return nil, err
+		}
+		return result, nil
 	}
 
-	_ = expectedHeaders // Used for documentation
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	rowNum := 1 // Start from 1 (after header)
-	var importedWins []Win
+	if err := applyImport(ctx, tx, plan, result); err != nil {
+		return nil, err
+	}
 
-	for {
-		rowNum++
-		record, err := csvReader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			result.Errors = append(result.Errors, ImportError{
-				Row:     rowNum,
-				Message: fmt.Sprintf("failed to read row: %v", err),
-			})
-			result.Skipped++
-			continue
-		}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 
+	s.logImport(ctx, result, userID, ipAddress)
+
+	return result, nil
+}
+
+// importAction is a write planned for one or more rows
+type importAction struct {
+	Rows     []int             `json:"rows"` // Later rows of the file may replace the values of the first
+	Request  *CreateWinRequest `json:"request"`
+	UpdateID int64             `json:"update_id,omitempty"` // Existing win to overwrite, otherwise inserted
+}
+
+// planImport parses and validates the CSV and decides what to do with each row without writing anything
+func (s *Service) planImport(ctx context.Context, rows []sourceRow, mode string) ([]importAction, *ImportResult, error) {
+	result := &ImportResult{
+		Mode:   mode,
+		Errors: []ImportError{},
+		Rows:   []ImportRowResult{},
+	}
+
+	matcher, err := loadMatcher(ctx, s.db)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := loadClassifier(ctx, s.db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var plan []importAction
+
+	for _, source := range rows {
+		rowNum := source.Row
 		result.Total++
 
-		// Parse row
-		row, err := parseCSVRow(record)
-		if err != nil {
-			result.Errors = append(result.Errors, ImportError{
-				Row:     rowNum,
-				Message: err.Error(),
-			})
-			result.Skipped++
+		if source.Err != nil {
+			result.fail(rowNum, source.Err.Error())
 			continue
 		}
+		row := importRowFromFields(source.Fields)
 
 		// Create win request
 		req := &CreateWinRequest{
@@ -75,10 +113,15 @@ func (s *Service) ImportCSV(ctx context.Context, reader io.Reader, userID int64,
 			HackathonName: row.HackathonName,
 			Result:        row.Result,
 			Prize:         parsePrize(row.Prize),
+			Currency:      row.Currency,
 			Year:          parseYear(row.Year),
 		}
 
 		if row.AwardDate != "" {
+			if _, err := parseDate(row.AwardDate); err != nil {
+				result.fail(rowNum, fmt.Sprintf("invalid award date %q", row.AwardDate))
+				continue
+			}
 			req.AwardDate = &row.AwardDate
 		}
 
@@ -88,118 +131,281 @@ func (s *Service) ImportCSV(ctx context.Context, reader io.Reader, userID int64,
 
 		// Validate
 		if err := req.Validate(); err != nil {
-			result.Errors = append(result.Errors, ImportError{
-				Row:     rowNum,
-				Message: err.Error(),
-			})
-			result.Skipped++
+			result.fail(rowNum, err.Error())
 			continue
 		}
 
-		// Create win (without individual audit logs)
-		win, err := s.createWithoutAudit(ctx, req)
-		if err != nil {
-			result.Errors = append(result.Errors, ImportError{
-				Row:     rowNum,
-				Message: fmt.Sprintf("failed to create: %v", err),
-			})
-			result.Skipped++
-			continue
+		outcome := ImportRowResult{Row: rowNum}
+
+		key := newWinKey(req.TeamName, req.HackathonName, req.Year, req.Result)
+		matchedID, match := matcher.find(key)
+
+		// Rows planned earlier in this file are indexed by -(action index + 1)
+		if matchedID < 0 {
+			action := &plan[-matchedID-1]
+			result.Duplicates++
+			outcome.Match = match
+
+			switch mode {
+			case ImportModeSkip:
+				outcome.Outcome = RowSkipped
+				outcome.Message = fmt.Sprintf("duplicate of row %d", action.Rows[0])
+				result.Skipped++
+				result.Rows = append(result.Rows, outcome)
+				continue
+			case ImportModeUpdate:
+				action.Rows = append(action.Rows, rowNum)
+				action.Request = req
+				outcome.Outcome = RowUpdated
+				outcome.Message = fmt.Sprintf("replaces the values of row %d", action.Rows[0])
+				result.Updated++
+				result.Rows = append(result.Rows, outcome)
+				continue
+			}
+			outcome.Message = fmt.Sprintf("duplicate of row %d", action.Rows[0])
 		}
 
-		importedWins = append(importedWins, *win)
+		if matchedID > 0 {
+			result.Duplicates++
+			outcome.Match = match
+			outcome.MatchedID = &matchedID
+
+			switch mode {
+			case ImportModeSkip:
+				outcome.Outcome = RowSkipped
+				outcome.Message = "win already exists"
+				result.Skipped++
+				result.Rows = append(result.Rows, outcome)
+				continue
+			case ImportModeUpdate:
+				existing, err := s.GetByID(ctx, matchedID)
+				if err != nil {
+					return nil, nil, err
+				}
+				plan = append(plan, importAction{Rows: []int{rowNum}, Request: req, UpdateID: matchedID})
+				outcome.Outcome = RowUpdated
+				outcome.Diff = importDiff(existing, req)
+				result.Updated++
+				result.Rows = append(result.Rows, outcome)
+				continue
+			}
+		}
+
+		plan = append(plan, importAction{Rows: []int{rowNum}, Request: req})
+		// Later rows of the same file are matched against this one too
+		matcher.add(key, -int64(len(plan)))
+
+		outcome.Outcome = RowInserted
+		outcome.Placement = c.classify(req.Result).Placement
 		result.Imported++
+		result.Rows = append(result.Rows, outcome)
+	}
+
+	return plan, result, nil
+}
+
+// applyImport writes the planned rows and fills in the IDs of the written wins.
+// Unless the mode inserts anyway, rows to insert are matched again against the wins at write time.
+// Returns ErrPreviewStale when a win to update no longer exists or a row to insert now has a match.
+func applyImport(ctx context.Context, q querier, plan []importAction, result *ImportResult) error {
+	c, err := loadClassifier(ctx, q)
+	if err != nil {
+		return err
 	}
 
-	// Single audit log for the entire import
-	if len(importedWins) > 0 {
-		importLog := map[string]any{
-			"action":   "bulk_import",
-			"total":    result.Total,
-			"imported": result.Imported,
-			"skipped":  result.Skipped,
+	var matcher *winMatcher
+	if result.Mode != ImportModeInsert {
+		// Hold off other writes to wins until commit so no match appears after the check
+		if _, err := q.Exec(ctx, "LOCK TABLE wins IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return fmt.Errorf("failed to lock wins: %w", err)
+		}
+		if matcher, err = loadMatcher(ctx, q); err != nil {
+			return err
 		}
-		s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityWin, nil, importLog, ipAddress)
 	}
 
-	return result, nil
-}
+	rows := make(map[int]*ImportRowResult, len(result.Rows))
+	for i := range result.Rows {
+		rows[result.Rows[i].Row] = &result.Rows[i]
+	}
 
-// createWithoutAudit creates a win without audit logging (for bulk import)
-func (s *Service) createWithoutAudit(ctx context.Context, req *CreateWinRequest) (*Win, error) {
-	// Parse award date
-	var awardDate interface{}
-	if req.AwardDate != nil && *req.AwardDate != "" {
-		parsed, err := parseDate(*req.AwardDate)
-		if err != nil {
-			return nil, err
+	for _, action := range plan {
+		id := action.UpdateID
+		if id != 0 {
+			_, err := applyUpdate(ctx, q, c, id, updateFromImport(action.Request))
+			if errors.Is(err, ErrWinNotFound) {
+				return fmt.Errorf("row %d: %w", action.Rows[0], ErrPreviewStale)
+			}
+			if err != nil {
+				return fmt.Errorf("row %d: failed to update: %w", action.Rows[0], err)
+			}
+		} else {
+			if matcher != nil {
+				req := action.Request
+				if matchedID, _ := matcher.find(newWinKey(req.TeamName, req.HackathonName, req.Year, req.Result)); matchedID > 0 {
+					return fmt.Errorf("row %d: matches win %d: %w", action.Rows[0], matchedID, ErrPreviewStale)
+				}
+			}
+			var err error
+			id, err = insertWin(ctx, q, c, action.Request)
+			if err != nil {
+				return fmt.Errorf("row %d: failed to create: %w", action.Rows[0], err)
+			}
+		}
+
+		for _, n := range action.Rows {
+			if row, ok := rows[n]; ok {
+				row.WinID = &id
+			}
 		}
-		awardDate = parsed
 	}
 
-	query := `
-		INSERT INTO wins (team_name, hackathon_name, result, prize, award_date, year, link, sort_order)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, team_name, hackathon_name, result, prize, award_date, year, link, sort_order, created_at, updated_at
-	`
+	return nil
+}
 
-	var w Win
-	err := s.db.QueryRow(ctx, query,
-		req.TeamName, req.HackathonName, req.Result, req.Prize,
-		awardDate, req.Year, req.Link, req.SortOrder,
-	).Scan(
-		&w.ID, &w.TeamName, &w.HackathonName, &w.Result, &w.Prize,
-		&w.AwardDate, &w.Year, &w.Link, &w.SortOrder, &w.CreatedAt, &w.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
+// logImport writes a single audit log for the entire import
+func (s *Service) logImport(ctx context.Context, result *ImportResult, userID int64, ipAddress string) {
+	if result.Imported == 0 && result.Updated == 0 {
+		return
+	}
+	importLog := map[string]any{
+		"action":     "bulk_import",
+		"mode":       result.Mode,
+		"total":      result.Total,
+		"imported":   result.Imported,
+		"updated":    result.Updated,
+		"skipped":    result.Skipped,
+		"duplicates": result.Duplicates,
 	}
+	if result.PreviewID != "" {
+		importLog["preview_id"] = result.PreviewID
+	}
+	s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityWin, nil, importLog, ipAddress)
+}
 
-	return &w, nil
+// fail records a row that could not be imported
+func (r *ImportResult) fail(row int, message string) {
+	r.Errors = append(r.Errors, ImportError{Row: row, Message: message})
+	r.Rows = append(r.Rows, ImportRowResult{Row: row, Outcome: RowError, Message: message})
+	r.Skipped++
 }
 
-// parseCSVRow parses a CSV record into CSVRow
-func parseCSVRow(record []string) (*CSVRow, error) {
-	if len(record) < 6 {
-		return nil, fmt.Errorf("row must have at least 6 columns, got %d", len(record))
+// importDiff lists the fields of w that req would change
+func importDiff(w *Win, req *CreateWinRequest) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	if w.TeamName != req.TeamName {
+		diff["team_name"] = FieldChange{w.TeamName, req.TeamName}
 	}
-
-	row := &CSVRow{
-		TeamName:      strings.TrimSpace(record[0]),
-		HackathonName: strings.TrimSpace(record[1]),
-		Result:        strings.TrimSpace(record[2]),
-		Prize:         strings.TrimSpace(record[3]),
-		AwardDate:     strings.TrimSpace(record[4]),
-		Year:          strings.TrimSpace(record[5]),
+	// Hackathons are matched case-insensitively
+	if !strings.EqualFold(w.HackathonName, strings.TrimSpace(req.HackathonName)) {
+		diff["hackathon_name"] = FieldChange{w.HackathonName, req.HackathonName}
 	}
+	if w.Result != req.Result {
+		diff["result"] = FieldChange{w.Result, req.Result}
+	}
+	if w.Prize != req.Prize {
+		diff["prize"] = FieldChange{w.Prize, req.Prize}
+	}
+	if w.Currency != req.Currency {
+		diff["currency"] = FieldChange{w.Currency, req.Currency}
+	}
+	if req.AwardDate != nil {
+		var before any
+		if w.AwardDate != nil {
+			before = w.AwardDate.Format("2006-01-02")
+		}
+		if parsed, err := parseDate(*req.AwardDate); err == nil {
+			if after := parsed.Format("2006-01-02"); before != after {
+				diff["award_date"] = FieldChange{before, after}
+			}
+		}
+	}
+	if w.Year != req.Year {
+		diff["year"] = FieldChange{w.Year, req.Year}
+	}
+	if req.Link != nil && (w.Link == nil || *w.Link != *req.Link) {
+		diff["link"] = FieldChange{w.Link, *req.Link}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	return diff
+}
 
-	// Link is optional (column 7)
-	if len(record) > 6 {
-		row.Link = strings.TrimSpace(record[6])
+// updateFromImport overwrites a matched win with the imported values.
+// Empty optional cells keep the existing award date and link.
+func updateFromImport(req *CreateWinRequest) *UpdateWinRequest {
+	return &UpdateWinRequest{
+		TeamName:      &req.TeamName,
+		HackathonName: &req.HackathonName,
+		Result:        &req.Result,
+		Prize:         &req.Prize,
+		Currency:      &req.Currency,
+		AwardDate:     req.AwardDate,
+		Year:          &req.Year,
+		Link:          req.Link,
 	}
+}
 
-	return row, nil
+// importRowFromFields collects the fields of a source row
+func importRowFromFields(fields map[string]string) *ImportRow {
+	return &ImportRow{
+		TeamName:      fields[fieldTeamName],
+		HackathonName: fields[fieldHackathonName],
+		Result:        fields[fieldResult],
+		Prize:         fields[fieldPrize],
+		AwardDate:     fields[fieldAwardDate],
+		Year:          fields[fieldYear],
+		Link:          fields[fieldLink],
+		Currency:      fields[fieldCurrency],
+	}
 }
 
+var (
+	// prizeFraction is a decimal part of one or two digits, after a comma ("170000,00") or a dot
+	prizeFraction = regexp.MustCompile(`[.,](\d{1,2})$`)
+	// prizeGroups is an integer part with a single kind of thousands separator
+	prizeGroups = regexp.MustCompile(`^\d{1,3}(,\d{3})+$|^\d{1,3}(\.\d{3})+$`)
+)
+
 // parsePrize parses prize string to int
-// Handles formats: "170000", "170 000", "170,000", "" (empty = 0)
+// Handles formats: "170000", "170 000", "170,000", "170.000", "170000.00", "170000,00", "170 000,50",
+// "" (empty = 0). A trailing separator with one or two digits is the decimal one; other commas and
+// dots are only accepted as thousands separators. Unparseable values give 0.
 func parsePrize(s string) int {
+	// Remove spaces (including non-breaking ones from Excel) and the currency sign
+	s = strings.ReplaceAll(s, " ", "")
+	s = strings.ReplaceAll(s, "\u00a0", "")
+	s = strings.ReplaceAll(s, "₽", "")
+	s = strings.TrimSpace(s)
 	if s == "" {
 		return 0
 	}
 
-	// Remove spaces and commas
-	s = strings.ReplaceAll(s, " ", "")
-	s = strings.ReplaceAll(s, ",", "")
-	s = strings.ReplaceAll(s, "₽", "")
-	s = strings.TrimSpace(s)
+	fraction := ""
+	if m := prizeFraction.FindStringSubmatchIndex(s); m != nil {
+		fraction = s[m[2]:m[3]]
+		s = s[:m[0]]
+	}
+	if strings.ContainsAny(s, ",.") {
+		if !prizeGroups.MatchString(s) {
+			return 0
+		}
+		s = strings.NewReplacer(",", "", ".", "").Replace(s)
+	}
 
-	prize, err := strconv.Atoi(s)
+	if fraction == "" {
+		prize, err := strconv.Atoi(s)
+		if err != nil {
+			return 0
+		}
+		return prize
+	}
+	amount, err := strconv.ParseFloat(s+"."+fraction, 64)
 	if err != nil {
 		return 0
 	}
-
-	return prize
+	return int(math.Round(amount))
 }
 
 // parseYear parses year string to int
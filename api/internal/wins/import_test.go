@@ -0,0 +1,33 @@
+package wins
+
+import "testing"
+
+func TestParsePrize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"170000", 170000},
+		{"170 000", 170000},
+		{"170 000 ₽", 170000},
+		{"170,000", 170000},
+		{"1,170,000", 1170000},
+		{"170.000", 170000},
+		{"170000.00", 170000},
+		{"170000,00", 170000},
+		{"170 000,50", 170001},
+		{"1,000.5", 1001},
+		{"1.000,25", 1000},
+		{"17,5", 18},
+		{"1,70,000", 0},
+		{"1,000.000", 0},
+		{"abc", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parsePrize(tt.in); got != tt.want {
+			t.Errorf("parsePrize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
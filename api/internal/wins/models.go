@@ -2,6 +2,9 @@ package wins
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -13,33 +16,98 @@ var (
 	ErrResultRequired    = errors.New("result is required")
 	ErrYearRequired      = errors.New("year is required")
 	ErrInvalidYear       = errors.New("invalid year")
+	ErrInvalidCurrency   = errors.New("invalid currency")
+	ErrHackathonNotFound = errors.New("hackathon not found")
+	ErrMemberNotFound    = errors.New("team member not found")
+	ErrPreviewNotFound   = errors.New("import preview not found or expired")
+	ErrPreviewStale      = errors.New("wins changed since the import preview")
+	ErrInvalidPlacement  = errors.New("invalid placement")
+	ErrInvalidRank       = errors.New("invalid rank")
+	ErrRuleNotFound      = errors.New("result rule not found")
+	ErrInvalidPattern    = errors.New("invalid rule pattern")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidBulkAction = errors.New("invalid bulk action")
+	ErrBulkIDsRequired   = errors.New("ids are required")
+	ErrBulkTooMany       = errors.New("too many ids")
+	ErrBulkNoChanges     = errors.New("bulk update sets no fields")
+	ErrInvalidImportMode = errors.New("invalid import mode")
+	ErrInvalidImportFile = errors.New("invalid import file")
 )
 
+// DefaultCurrency is used for prizes without an explicit currency
+const DefaultCurrency = "RUB"
+
+// currencyPattern matches ISO 4217 codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// normalizeCurrency upper-cases a currency code, defaulting to rubles
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultCurrency, nil
+	}
+	if !currencyPattern.MatchString(currency) {
+		return "", ErrInvalidCurrency
+	}
+	return currency, nil
+}
+
 // Win represents a hackathon victory
 type Win struct {
-	ID            int64      `json:"id"`
-	TeamName      string     `json:"team_name"`
-	HackathonName string     `json:"hackathon_name"`
-	Result        string     `json:"result"`
-	Prize         int        `json:"prize"`
-	AwardDate     *time.Time `json:"award_date"`
-	Year          int        `json:"year"`
-	Link          *string    `json:"link"`
-	SortOrder     int        `json:"sort_order"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-}
-
-// CreateWinRequest is the request body for creating a win
+	ID              int64         `json:"id"`
+	TeamName        string        `json:"team_name"`
+	HackathonID     int64         `json:"hackathon_id"`
+	HackathonName   string        `json:"hackathon_name"`
+	Result          string        `json:"result"`
+	Prize           int           `json:"prize"`
+	Currency        string        `json:"currency"`
+	AwardDate       *time.Time    `json:"award_date"`
+	Year            int           `json:"year"`
+	Link            *string       `json:"link"`
+	SortOrder       int           `json:"sort_order"`
+	Placement       *string       `json:"placement"` // Null until classified
+	Rank            *int          `json:"rank"`
+	PlacementManual bool          `json:"placement_manual"` // Set by an admin instead of the rules
+	Participants    []Participant `json:"participants"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// Participant is a team member who took part in a win
+type Participant struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Photo *string `json:"photo"`
+}
+
+// CreateWinRequest is the request body for creating a win.
+// The hackathon is given by ID, or by name: an existing hackathon is matched case-insensitively, otherwise one is created.
 type CreateWinRequest struct {
-	TeamName      string  `json:"team_name"`
-	HackathonName string  `json:"hackathon_name"`
-	Result        string  `json:"result"`
-	Prize         int     `json:"prize"`
-	AwardDate     *string `json:"award_date"` // Format: YYYY-MM-DD or DD.MM.YYYY
-	Year          int     `json:"year"`
-	Link          *string `json:"link"`
-	SortOrder     int     `json:"sort_order"`
+	TeamName       string  `json:"team_name"`
+	HackathonID    *int64  `json:"hackathon_id"`
+	HackathonName  string  `json:"hackathon_name"`
+	Result         string  `json:"result"`
+	Prize          int     `json:"prize"`
+	Currency       string  `json:"currency"`   // ISO 4217, default RUB
+	AwardDate      *string `json:"award_date"` // Format: YYYY-MM-DD or DD.MM.YYYY
+	Year           int     `json:"year"`
+	Link           *string `json:"link"`
+	SortOrder      int     `json:"sort_order"`
+	Placement      string  `json:"placement"`       // Classified from the result by the rules when empty
+	Rank           *int    `json:"rank"`            // Required for "place"; podium placements get 1-3
+	ParticipantIDs []int64 `json:"participant_ids"` // team_members
+
+	Hackathon *HackathonDetails `json:"-"` // Stored when the hackathon is created by name
+}
+
+// HackathonDetails describe a hackathon created along with a win, e.g. from an event
+type HackathonDetails struct {
+	Organizer *string
+	City      *string // nil for online-only hackathons
+	IsOnline  bool
+	StartDate *time.Time
+	EndDate   *time.Time
+	URL       *string
 }
 
 // Validate validates the create win request
@@ -47,7 +115,7 @@ func (r *CreateWinRequest) Validate() error {
 	if r.TeamName == "" {
 		return ErrTeamNameRequired
 	}
-	if r.HackathonName == "" {
+	if r.HackathonID == nil && strings.TrimSpace(r.HackathonName) == "" {
 		return ErrHackathonRequired
 	}
 	if r.Result == "" {
@@ -59,19 +127,29 @@ func (r *CreateWinRequest) Validate() error {
 	if r.Year < 2000 || r.Year > 2100 {
 		return ErrInvalidYear
 	}
-	return nil
+	currency, err := normalizeCurrency(r.Currency)
+	if err != nil {
+		return err
+	}
+	r.Currency = currency
+	return validateManualPlacement(r.Placement, r.Rank)
 }
 
 // UpdateWinRequest is the request body for updating a win
 type UpdateWinRequest struct {
-	TeamName      *string `json:"team_name,omitempty"`
-	HackathonName *string `json:"hackathon_name,omitempty"`
-	Result        *string `json:"result,omitempty"`
-	Prize         *int    `json:"prize,omitempty"`
-	AwardDate     *string `json:"award_date,omitempty"`
-	Year          *int    `json:"year,omitempty"`
-	Link          *string `json:"link,omitempty"`
-	SortOrder     *int    `json:"sort_order,omitempty"`
+	TeamName       *string  `json:"team_name,omitempty"`
+	HackathonID    *int64   `json:"hackathon_id,omitempty"`
+	HackathonName  *string  `json:"hackathon_name,omitempty"` // Ignored when hackathon_id is set
+	Result         *string  `json:"result,omitempty"`
+	Prize          *int     `json:"prize,omitempty"`
+	Currency       *string  `json:"currency,omitempty"`
+	AwardDate      *string  `json:"award_date,omitempty"`
+	Year           *int     `json:"year,omitempty"`
+	Link           *string  `json:"link,omitempty"`
+	SortOrder      *int     `json:"sort_order,omitempty"`
+	Placement      *string  `json:"placement,omitempty"`       // Empty returns to classifying by the rules
+	Rank           *int     `json:"rank,omitempty"`            // Only together with placement
+	ParticipantIDs *[]int64 `json:"participant_ids,omitempty"` // Replaces the participants
 }
 
 // Validate validates the update win request
@@ -79,7 +157,7 @@ func (r *UpdateWinRequest) Validate() error {
 	if r.TeamName != nil && *r.TeamName == "" {
 		return ErrTeamNameRequired
 	}
-	if r.HackathonName != nil && *r.HackathonName == "" {
+	if r.HackathonID == nil && r.HackathonName != nil && strings.TrimSpace(*r.HackathonName) == "" {
 		return ErrHackathonRequired
 	}
 	if r.Result != nil && *r.Result == "" {
@@ -88,17 +166,64 @@ func (r *UpdateWinRequest) Validate() error {
 	if r.Year != nil && (*r.Year < 2000 || *r.Year > 2100) {
 		return ErrInvalidYear
 	}
+	if r.Currency != nil {
+		currency, err := normalizeCurrency(*r.Currency)
+		if err != nil {
+			return err
+		}
+		r.Currency = &currency
+	}
+	if r.Placement == nil {
+		if r.Rank != nil {
+			return fmt.Errorf("%w: rank is set together with placement", ErrInvalidRank)
+		}
+		return nil
+	}
+	return validateManualPlacement(*r.Placement, r.Rank)
+}
+
+// validateManualPlacement checks a placement set by an admin; empty means classified by the rules
+func validateManualPlacement(placement string, rank *int) error {
+	if placement == "" {
+		if rank != nil {
+			return fmt.Errorf("%w: rank is set together with placement", ErrInvalidRank)
+		}
+		return nil
+	}
+	if !ValidPlacement(placement) {
+		return ErrInvalidPlacement
+	}
+	if rank != nil && *rank < 1 {
+		return ErrInvalidRank
+	}
+	if placement == PlaceNumbered && rank == nil {
+		return fmt.Errorf("%w: place needs a rank", ErrInvalidRank)
+	}
 	return nil
 }
 
 // ListWinsParams contains parameters for listing wins
 type ListWinsParams struct {
-	Page     int
-	PageSize int
-	Search   string
-	Year     int
+	Page        int
+	PageSize    int
+	Search      string
+	Year        int
+	HackathonID int64
+	Placements  []string // Any of these placements
+	PrizeMin    *int     // Prize range in any currency, inclusive
+	PrizeMax    *int
+	Sort        string // date (default), placement or prize
+	Cursor      string // Public list: position after the previous page
+	Limit       int    // Public list page size
 }
 
+// Sort orders of wins lists
+const (
+	SortDate      = "date"      // Newest first
+	SortPlacement = "placement" // Best placement first, then newest
+	SortPrize     = "prize"     // Largest prize first, then newest
+)
+
 // ListWinsResponse is the response for listing wins
 type ListWinsResponse struct {
 	Wins       []Win `json:"wins"`
@@ -108,12 +233,27 @@ type ListWinsResponse struct {
 	TotalPages int   `json:"total_pages"`
 }
 
-// ImportResult represents the result of a CSV import
+// ImportOptions controls how an import is applied
+type ImportOptions struct {
+	Mode   string // skip, update or insert for rows matching an existing win
+	Format string // csv, xlsx or json; detected from the content if empty
+	DryRun bool   // Only validate and store a preview to commit later
+}
+
+// ImportResult represents the result of a CSV import.
+// For a dry run the counts and row outcomes describe what committing the preview would do.
 type ImportResult struct {
-	Total    int            `json:"total"`
-	Imported int            `json:"imported"`
-	Skipped  int            `json:"skipped"`
-	Errors   []ImportError  `json:"errors,omitempty"`
+	Mode       string            `json:"mode"`
+	DryRun     bool              `json:"dry_run"`
+	PreviewID  string            `json:"preview_id,omitempty"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"` // Preview expiry
+	Total      int               `json:"total"`
+	Imported   int               `json:"imported"`
+	Updated    int               `json:"updated"`
+	Skipped    int               `json:"skipped"`
+	Duplicates int               `json:"duplicates"` // Rows matching an existing win
+	Errors     []ImportError     `json:"errors,omitempty"`
+	Rows       []ImportRowResult `json:"rows"`
 }
 
 // ImportError represents an error during import
@@ -122,8 +262,26 @@ type ImportError struct {
 	Message string `json:"message"`
 }
 
-// CSVRow represents a row from the CSV file
-type CSVRow struct {
+// ImportRowResult is the outcome of a single imported row
+type ImportRowResult struct {
+	Row       int                    `json:"row"`
+	Outcome   string                 `json:"outcome"`              // inserted, updated, skipped or error
+	WinID     *int64                 `json:"win_id,omitempty"`     // Inserted or updated win
+	Match     string                 `json:"match,omitempty"`      // exact or fuzzy
+	MatchedID *int64                 `json:"matched_id,omitempty"` // Existing win the row matched
+	Placement string                 `json:"placement,omitempty"`  // Classification of an inserted row
+	Diff      map[string]FieldChange `json:"diff,omitempty"`       // Changes to the matched win
+	Message   string                 `json:"message,omitempty"`
+}
+
+// FieldChange is a field value an import replaces
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// ImportRow represents a row of an imported file
+type ImportRow struct {
 	TeamName      string
 	HackathonName string
 	Result        string
@@ -131,4 +289,5 @@ type CSVRow struct {
 	AwardDate     string
 	Year          string
 	Link          string
+	Currency      string
 }
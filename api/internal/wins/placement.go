@@ -0,0 +1,447 @@
+package wins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/itam-misis/itam-api/internal/audit"
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// Placements a free-form result is classified as, from best to worst
+const (
+	PlaceFirst       = "first"
+	PlaceSecond      = "second"
+	PlaceThird       = "third"
+	PlaceNumbered    = "place" // 4th place and below, the rank holds the number
+	PlacePrizewinner = "prizewinner"
+	PlaceSpecial     = "special" // Special prizes and nominations
+	PlaceFinalist    = "finalist"
+	PlaceOther       = "other" // Results no rule matches
+)
+
+// Placements lists all placements from best to worst; public sorting follows this order
+var Placements = []string{
+	PlaceFirst, PlaceSecond, PlaceThird, PlaceNumbered, PlacePrizewinner, PlaceSpecial, PlaceFinalist, PlaceOther,
+}
+
+// placementPosition is the position of a win's placement in Placements, unclassified wins last
+const placementPosition = `COALESCE(array_position(ARRAY['first', 'second', 'third', 'place', 'prizewinner', 'special', 'finalist', 'other'], w.placement), 9)`
+
+// podium maps ranks to the top three placements and back
+var (
+	podiumByRank   = map[int]string{1: PlaceFirst, 2: PlaceSecond, 3: PlaceThird}
+	podiumRank     = map[string]int{PlaceFirst: 1, PlaceSecond: 2, PlaceThird: 3}
+	validPlacement = func() map[string]bool {
+		m := map[string]bool{}
+		for _, p := range Placements {
+			m[p] = true
+		}
+		return m
+	}()
+)
+
+// ValidPlacement reports whether p is a known placement
+func ValidPlacement(p string) bool {
+	return validPlacement[p]
+}
+
+// ResultRule classifies results matching Pattern as Placement.
+// A "place" rule takes the rank from the first capture group of the pattern, or from Rank.
+type ResultRule struct {
+	ID        int64     `json:"id"`
+	Pattern   string    `json:"pattern"` // Matched against the result lower-cased, with ё as е and punctuation as spaces
+	Placement string    `json:"placement"`
+	Rank      *int      `json:"rank"`
+	Priority  int       `json:"priority"` // Higher priorities are tried first
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ResultRuleRequest is the request body for creating or replacing a rule
+type ResultRuleRequest struct {
+	Pattern   string `json:"pattern"`
+	Placement string `json:"placement"`
+	Rank      *int   `json:"rank"`
+	Priority  int    `json:"priority"`
+}
+
+// Validate checks that the pattern compiles and that a "place" rule can tell its rank
+func (r *ResultRuleRequest) Validate() error {
+	re, err := regexp.Compile(r.Pattern)
+	if r.Pattern == "" || err != nil {
+		return ErrInvalidPattern
+	}
+	if !ValidPlacement(r.Placement) {
+		return ErrInvalidPlacement
+	}
+	if r.Rank != nil && *r.Rank < 1 {
+		return ErrInvalidRank
+	}
+	if r.Placement == PlaceNumbered && r.Rank == nil && re.NumSubexp() == 0 {
+		return fmt.Errorf("%w: a place rule needs a rank or a capture group", ErrInvalidRank)
+	}
+	return nil
+}
+
+// RulesResponse lists the rules with the number of wins reclassified by a change
+type RulesResponse struct {
+	Rules        []ResultRule `json:"rules"`
+	Reclassified int          `json:"reclassified"`
+}
+
+// placementResult is the classification of one result
+type placementResult struct {
+	Placement string
+	Rank      *int
+}
+
+type compiledRule struct {
+	re        *regexp.Regexp
+	placement string
+	rank      *int
+}
+
+// classifier applies the rules in priority order
+type classifier struct {
+	rules []compiledRule
+}
+
+// loadClassifier compiles the current rules; rules that no longer compile are skipped
+func loadClassifier(ctx context.Context, q querier) (*classifier, error) {
+	rules, err := listRules(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &classifier{}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			slog.Warn("skipping invalid result rule", "id", rule.ID, "error", err)
+			continue
+		}
+		c.rules = append(c.rules, compiledRule{re: re, placement: rule.Placement, rank: rule.Rank})
+	}
+	return c, nil
+}
+
+// classify returns the placement of the first matching rule, or other
+func (c *classifier) classify(result string) placementResult {
+	normalized := normalizeText(result)
+	for _, rule := range c.rules {
+		m := rule.re.FindStringSubmatch(normalized)
+		if m == nil {
+			continue
+		}
+
+		rank := rule.rank
+		if rule.placement == PlaceNumbered && len(m) > 1 {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+				rank = &n
+			}
+		}
+		return placementWithRank(rule.placement, rank)
+	}
+	return placementResult{Placement: PlaceOther}
+}
+
+// placementWithRank fills in the rank of podium places and turns numbered places 1-3 into them
+func placementWithRank(placement string, rank *int) placementResult {
+	if placement == PlaceNumbered && rank != nil {
+		if p, ok := podiumByRank[*rank]; ok {
+			placement = p
+		}
+	}
+	if n, ok := podiumRank[placement]; ok {
+		rank = &n
+	}
+	return placementResult{Placement: placement, Rank: rank}
+}
+
+// resolvePlacement returns the manual placement when given, otherwise classifies the result
+func (c *classifier) resolvePlacement(result, manual string, rank *int) (placementResult, bool) {
+	if manual != "" {
+		return placementWithRank(manual, rank), true
+	}
+	return c.classify(result), false
+}
+
+// listRules returns the rules in the order they are tried
+func listRules(ctx context.Context, q querier) ([]ResultRule, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, pattern, placement, rank, priority, created_at, updated_at
+		FROM win_result_rules
+		ORDER BY priority DESC, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query result rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []ResultRule{}
+	for rows.Next() {
+		var rule ResultRule
+		if err := rows.Scan(&rule.ID, &rule.Pattern, &rule.Placement, &rule.Rank, &rule.Priority, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan result rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// ListRules returns the result rules
+func (s *Service) ListRules(ctx context.Context) ([]ResultRule, error) {
+	return listRules(ctx, s.db)
+}
+
+// CreateRule adds a rule and reclassifies the wins without a manual placement
+func (s *Service) CreateRule(ctx context.Context, req *ResultRuleRequest, userID int64, ipAddress string) (*RulesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO win_result_rules (pattern, placement, rank, priority)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, req.Pattern, req.Placement, req.Rank, req.Priority).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create result rule: %w", err)
+	}
+
+	return s.rulesChanged(ctx, audit.ActionCreate, id, req, userID, ipAddress)
+}
+
+// UpdateRule replaces a rule and reclassifies the wins without a manual placement
+func (s *Service) UpdateRule(ctx context.Context, id int64, req *ResultRuleRequest, userID int64, ipAddress string) (*RulesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.Exec(ctx, `
+		UPDATE win_result_rules SET pattern = $1, placement = $2, rank = $3, priority = $4
+		WHERE id = $5
+	`, req.Pattern, req.Placement, req.Rank, req.Priority, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update result rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrRuleNotFound
+	}
+
+	return s.rulesChanged(ctx, audit.ActionUpdate, id, req, userID, ipAddress)
+}
+
+// DeleteRule removes a rule and reclassifies the wins without a manual placement
+func (s *Service) DeleteRule(ctx context.Context, id int64, userID int64, ipAddress string) (*RulesResponse, error) {
+	var deleted ResultRule
+	err := s.db.QueryRow(ctx, `
+		DELETE FROM win_result_rules WHERE id = $1
+		RETURNING id, pattern, placement, rank, priority, created_at, updated_at
+	`, id).Scan(&deleted.ID, &deleted.Pattern, &deleted.Placement, &deleted.Rank, &deleted.Priority, &deleted.CreatedAt, &deleted.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete result rule: %w", err)
+	}
+
+	return s.rulesChanged(ctx, audit.ActionDelete, id, deleted, userID, ipAddress)
+}
+
+// rulesChanged reclassifies the wins, logs the change and returns the rules
+func (s *Service) rulesChanged(ctx context.Context, action string, id int64, rule any, userID int64, ipAddress string) (*RulesResponse, error) {
+	reclassified, err := s.Reclassify(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogAction(ctx, &userID, action, audit.EntityWinRule, &id, map[string]any{
+		"rule":         rule,
+		"reclassified": reclassified,
+	}, ipAddress)
+
+	rules, err := s.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &RulesResponse{Rules: rules, Reclassified: reclassified}, nil
+}
+
+// Reclassify applies the current rules to the wins without a manual placement,
+// or only to the wins not classified yet, and returns the number of wins that changed
+func (s *Service) Reclassify(ctx context.Context, pendingOnly bool) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := loadClassifier(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, result, placement, rank FROM wins
+		WHERE NOT placement_manual AND (NOT $1 OR placement IS NULL)
+		FOR UPDATE
+	`, pendingOnly)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query wins: %w", err)
+	}
+
+	changed := map[int64]placementResult{}
+	for rows.Next() {
+		var id int64
+		var result string
+		var placement *string
+		var rank *int
+		if err := rows.Scan(&id, &result, &placement, &rank); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan win: %w", err)
+		}
+		p := c.classify(result)
+		if placement == nil || *placement != p.Placement || !equalRank(rank, p.Rank) {
+			changed[id] = p
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query wins: %w", err)
+	}
+
+	for id, p := range changed {
+		if _, err := tx.Exec(ctx, "UPDATE wins SET placement = $1, rank = $2 WHERE id = $3", p.Placement, p.Rank, id); err != nil {
+			return 0, fmt.Errorf("failed to classify win %d: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(changed), nil
+}
+
+// ClassifyPending classifies the wins stored before placements existed; called on startup
+func (s *Service) ClassifyPending(ctx context.Context) {
+	n, err := s.Reclassify(ctx, true)
+	if err != nil {
+		slog.Error("failed to classify wins", "error", err)
+		return
+	}
+	if n > 0 {
+		slog.Info("classified wins", "count", n)
+	}
+}
+
+func equalRank(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ListRules handles GET /api/wins/rules
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListRules(r.Context())
+	if err != nil {
+		slog.Error("failed to list result rules", "error", err)
+		response.InternalError(w, "failed to list rules")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, rules)
+}
+
+// CreateRule handles POST /api/wins/rules
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req ResultRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.CreateRule(r.Context(), &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeRuleError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, result)
+}
+
+// UpdateRule handles PUT /api/wins/rules/:id
+func (h *Handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid rule id")
+		return
+	}
+
+	var req ResultRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.UpdateRule(r.Context(), id, &req, userID, r.RemoteAddr)
+	if err != nil {
+		writeRuleError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// DeleteRule handles DELETE /api/wins/rules/:id
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid rule id")
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.DeleteRule(r.Context(), id, userID, r.RemoteAddr)
+	if err != nil {
+		writeRuleError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+func writeRuleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrRuleNotFound):
+		response.NotFound(w, "rule not found")
+	case errors.Is(err, ErrInvalidPattern):
+		response.ValidationError(w, "pattern must be a valid regular expression")
+	case errors.Is(err, ErrInvalidPlacement):
+		response.ValidationError(w, "placement must be one of first, second, third, place, prizewinner, special, finalist, other")
+	case errors.Is(err, ErrInvalidRank):
+		response.ValidationError(w, err.Error())
+	default:
+		slog.Error("failed to change result rule", "error", err)
+		response.InternalError(w, "failed to change rule")
+	}
+}
@@ -0,0 +1,131 @@
+package wins
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/itam-misis/itam-api/internal/auth"
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// previewTTL is how long a dry-run import can be committed
+const previewTTL = "1 hour"
+
+// savePreview stores a dry-run plan and marks the result as its preview
+func (s *Service) savePreview(ctx context.Context, plan []importAction, result *ImportResult, userID int64) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	result.DryRun = true
+	result.PreviewID = hex.EncodeToString(id)
+
+	if plan == nil {
+		plan = []importAction{}
+	}
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	// Expired previews are never committed
+	if _, err := s.db.Exec(ctx, "DELETE FROM win_import_previews WHERE expires_at < NOW()"); err != nil {
+		return fmt.Errorf("failed to clean up import previews: %w", err)
+	}
+
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO win_import_previews (id, mode, plan, result, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + $6::interval)
+		RETURNING expires_at
+	`, result.PreviewID, result.Mode, planJSON, resultJSON, userID, previewTTL).Scan(&result.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save import preview: %w", err)
+	}
+
+	return nil
+}
+
+// CommitImport applies a dry-run import of the user in one transaction; nothing is written if any row fails.
+// A preview is committed at most once.
+func (s *Service) CommitImport(ctx context.Context, previewID string, userID int64, ipAddress string) (*ImportResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var planJSON, resultJSON []byte
+	err = tx.QueryRow(ctx, `
+		SELECT plan, result FROM win_import_previews
+		WHERE id = $1 AND created_by = $2 AND expires_at > NOW()
+		FOR UPDATE
+	`, previewID, userID).Scan(&planJSON, &resultJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPreviewNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load import preview: %w", err)
+	}
+
+	var plan []importAction
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode import preview: %w", err)
+	}
+	var result ImportResult
+	if err := json.Unmarshal(resultJSON, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode import preview: %w", err)
+	}
+	result.DryRun = false
+	result.ExpiresAt = nil
+
+	if err := applyImport(ctx, tx, plan, &result); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM win_import_previews WHERE id = $1", previewID); err != nil {
+		return nil, fmt.Errorf("failed to delete import preview: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	s.logImport(ctx, &result, userID, ipAddress)
+
+	return &result, nil
+}
+
+// CommitImport handles POST /api/wins/import/:id/commit
+func (h *Handler) CommitImport(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.CommitImport(r.Context(), chi.URLParam(r, "id"), userID, r.RemoteAddr)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPreviewNotFound):
+			response.NotFound(w, "import preview not found or expired")
+		case errors.Is(err, ErrPreviewStale):
+			response.Conflict(w, err.Error()+", run the dry run again")
+		default:
+			slog.Error("failed to commit wins import", "error", err)
+			response.InternalError(w, "failed to commit import")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
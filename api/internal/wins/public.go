@@ -0,0 +1,385 @@
+package wins
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/itam-misis/itam-api/internal/response"
+)
+
+// Public list page sizes
+const (
+	DefaultPublicLimit = 20
+	MaxPublicLimit     = 100
+	// MaxSearchLength bounds the free-text search, which is part of the cache key
+	MaxSearchLength = 100
+)
+
+// dateKeys order the newest wins first; unknown award dates come after known ones of the same year
+var dateKeys = []string{
+	"-w.year",
+	"-COALESCE(w.sort_order, 0)",
+	"-COALESCE(w.award_date - DATE '1970-01-01', -1000000)",
+	"-w.id",
+}
+
+// sortKeys are ascending integer expressions for each sort order. They end with the ID,
+// so that every win has a unique position and a cursor can hold the keys of the last win of a page.
+var sortKeys = map[string][]string{
+	SortDate:      dateKeys,
+	SortPlacement: append([]string{placementPosition, "COALESCE(w.rank, 2147483647)"}, dateKeys...),
+	SortPrize:     append([]string{"-COALESCE(w.prize, 0)"}, dateKeys...),
+}
+
+// orderBy returns the ORDER BY clause for a sort order, newest first by default
+func orderBy(sort string) string {
+	keys, ok := sortKeys[sort]
+	if !ok {
+		keys = sortKeys[SortDate]
+	}
+	return strings.Join(keys, ", ")
+}
+
+// cursor is the position of the last win of a page
+type cursor struct {
+	Sort string  `json:"s"`
+	Keys []int64 `json:"k"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor issued for the same sort order
+func decodeCursor(s, sort string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || len(c.Keys) != len(sortKeys[sort]) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// PublicWinsResponse is a page of public wins
+type PublicWinsResponse struct {
+	Wins       []Win     `json:"wins"`
+	Total      int       `json:"total"`       // Wins matching the filters
+	NextCursor *string   `json:"next_cursor"` // Null on the last page
+	Facets     WinFacets `json:"facets"`
+}
+
+// WinFacets count the wins per value of a filter. Each facet applies all other filters
+// but not its own, so that e.g. year tabs show what selecting each year would return.
+type WinFacets struct {
+	Years      []YearFacet      `json:"years"`      // Newest first
+	Placements []PlacementFacet `json:"placements"` // Best first
+	Hackathons []HackathonFacet `json:"hackathons"` // Most wins first
+}
+
+// YearFacet is the number of wins in a year
+type YearFacet struct {
+	Year  int `json:"year"`
+	Count int `json:"count"`
+}
+
+// PlacementFacet is the number of wins with a placement
+type PlacementFacet struct {
+	Placement string `json:"placement"`
+	Count     int    `json:"count"`
+}
+
+// HackathonFacet is the number of wins at a hackathon
+type HackathonFacet struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ListPublic returns a page of wins matching the filters of params, with facet counts
+func (s *Service) ListPublic(ctx context.Context, params ListWinsParams) (*PublicWinsResponse, error) {
+	if params.Limit < 1 || params.Limit > MaxPublicLimit {
+		params.Limit = DefaultPublicLimit
+	}
+	if params.Sort == "" {
+		params.Sort = SortDate
+	}
+	keys := sortKeys[params.Sort]
+
+	var after *cursor
+	if params.Cursor != "" {
+		var err error
+		if after, err = decodeCursor(params.Cursor, params.Sort); err != nil {
+			return nil, err
+		}
+	}
+
+	baseQuery, args := filterQuery(params)
+
+	resp := &PublicWinsResponse{Wins: []Win{}}
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&resp.Total); err != nil {
+		return nil, fmt.Errorf("failed to count wins: %w", err)
+	}
+
+	keyColumns := make([]string, len(keys))
+	for i, key := range keys {
+		keyColumns[i] = "(" + key + ")::bigint"
+	}
+	keyRow := strings.Join(keyColumns, ", ")
+
+	pageQuery := baseQuery
+	if after != nil {
+		placeholders := make([]string, len(after.Keys))
+		for i, key := range after.Keys {
+			args = append(args, key)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		pageQuery += fmt.Sprintf(" AND (%s) > (%s)", keyRow, strings.Join(placeholders, ", "))
+	}
+	args = append(args, params.Limit+1)
+
+	rows, err := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT %s, ARRAY[%s]
+		%s
+		ORDER BY %s
+		LIMIT $%d
+	`, winColumns(publicParticipants), keyRow, pageQuery, orderBy(params.Sort), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wins: %w", err)
+	}
+	defer rows.Close()
+
+	var lastKeys []int64
+	for rows.Next() {
+		var w Win
+		var rowKeys []int64
+		if err := scanWin(rows, &w, &rowKeys); err != nil {
+			return nil, fmt.Errorf("failed to scan win: %w", err)
+		}
+		if len(resp.Wins) == params.Limit {
+			next := encodeCursor(cursor{Sort: params.Sort, Keys: lastKeys})
+			resp.NextCursor = &next
+			break
+		}
+		resp.Wins = append(resp.Wins, w)
+		lastKeys = rowKeys
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query wins: %w", err)
+	}
+	rows.Close()
+
+	facets, err := s.facets(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	resp.Facets = *facets
+
+	return resp, nil
+}
+
+// facets counts the wins per year, placement and hackathon
+func (s *Service) facets(ctx context.Context, params ListWinsParams) (*WinFacets, error) {
+	f := &WinFacets{
+		Years:      []YearFacet{},
+		Placements: []PlacementFacet{},
+		Hackathons: []HackathonFacet{},
+	}
+
+	byYear := params
+	byYear.Year = 0
+	err := s.facet(ctx, byYear, "SELECT w.year, COUNT(*) %s GROUP BY w.year ORDER BY w.year DESC", func(rows pgx.Rows) error {
+		var y YearFacet
+		if err := rows.Scan(&y.Year, &y.Count); err != nil {
+			return err
+		}
+		f.Years = append(f.Years, y)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count wins by year: %w", err)
+	}
+
+	byPlacement := params
+	byPlacement.Placements = nil
+	counts := map[string]int{}
+	err = s.facet(ctx, byPlacement, "SELECT COALESCE(w.placement, '"+PlaceOther+"'), COUNT(*) %s GROUP BY 1", func(rows pgx.Rows) error {
+		var placement string
+		var count int
+		if err := rows.Scan(&placement, &count); err != nil {
+			return err
+		}
+		counts[placement] += count
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count wins by placement: %w", err)
+	}
+	for _, placement := range Placements {
+		if counts[placement] > 0 {
+			f.Placements = append(f.Placements, PlacementFacet{Placement: placement, Count: counts[placement]})
+		}
+	}
+
+	byHackathon := params
+	byHackathon.HackathonID = 0
+	err = s.facet(ctx, byHackathon, "SELECT h.id, h.name, COUNT(*) %s GROUP BY h.id, h.name ORDER BY 3 DESC, 2", func(rows pgx.Rows) error {
+		var h HackathonFacet
+		if err := rows.Scan(&h.ID, &h.Name, &h.Count); err != nil {
+			return err
+		}
+		f.Hackathons = append(f.Hackathons, h)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count wins by hackathon: %w", err)
+	}
+
+	return f, nil
+}
+
+// facet runs a grouped count; query has a %s for the FROM and WHERE clauses of params
+func (s *Service) facet(ctx context.Context, params ListWinsParams, query string, scan func(pgx.Rows) error) error {
+	baseQuery, args := filterQuery(params)
+	rows, err := s.db.Query(ctx, fmt.Sprintf(query, baseQuery), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListPublic handles GET /api/public/wins
+// ?year=&placement=first,second&hackathon_id=&search=&prize_min=&prize_max=&sort=date|placement|prize&limit=&cursor=
+func (h *Handler) ListPublic(w http.ResponseWriter, r *http.Request) {
+	params, err := publicParams(r)
+	if err != nil {
+		response.ValidationError(w, err.Error())
+		return
+	}
+
+	result, err := h.service.ListPublic(r.Context(), params)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			response.ValidationError(w, "invalid cursor")
+			return
+		}
+		slog.Error("failed to list public wins", "error", err)
+		response.InternalError(w, "failed to list wins")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// publicParams parses the filters of List plus the prize range and the page position
+func publicParams(r *http.Request) (ListWinsParams, error) {
+	params, err := listParams(r)
+	if err != nil {
+		return params, err
+	}
+
+	if utf8.RuneCountInString(params.Search) > MaxSearchLength {
+		return params, fmt.Errorf("search must be at most %d characters", MaxSearchLength)
+	}
+
+	query := r.URL.Query()
+	for name, dest := range map[string]**int{"prize_min": &params.PrizeMin, "prize_max": &params.PrizeMax} {
+		if v := query.Get(name); v != "" {
+			prize, err := strconv.Atoi(v)
+			if err != nil || prize < 0 {
+				return params, fmt.Errorf("%s must be a non-negative integer", name)
+			}
+			*dest = &prize
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > MaxPublicLimit {
+			return params, fmt.Errorf("limit must be between 1 and %d", MaxPublicLimit)
+		}
+		params.Limit = l
+	}
+
+	if params.Cursor = query.Get("cursor"); params.Cursor != "" {
+		sort := params.Sort
+		if sort == "" {
+			sort = SortDate
+		}
+		if _, err := decodeCursor(params.Cursor, sort); err != nil {
+			return params, err
+		}
+	}
+
+	return params, nil
+}
+
+// PublicCacheKey builds the cache key of GET /api/public/wins from the parsed parameters, so
+// spellings of the same query share an entry and ignored values never make a new one.
+// Invalid queries get a key that is never stored, as only successful responses are cached.
+func PublicCacheKey(prefix string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		params, err := publicParams(r)
+		if err != nil {
+			return prefix + ":invalid"
+		}
+
+		values := url.Values{}
+		if params.Year != 0 {
+			values.Set("year", strconv.Itoa(params.Year))
+		}
+		if params.HackathonID != 0 {
+			values.Set("hackathon_id", strconv.FormatInt(params.HackathonID, 10))
+		}
+		if len(params.Placements) > 0 {
+			values.Set("placement", strings.Join(params.Placements, ","))
+		}
+		if params.Search != "" {
+			values.Set("search", strings.ToLower(params.Search)) // Matched case-insensitively
+		}
+		if params.PrizeMin != nil {
+			values.Set("prize_min", strconv.Itoa(*params.PrizeMin))
+		}
+		if params.PrizeMax != nil {
+			values.Set("prize_max", strconv.Itoa(*params.PrizeMax))
+		}
+		if params.Sort != "" && params.Sort != SortDate {
+			values.Set("sort", params.Sort)
+		}
+		if params.Limit != 0 && params.Limit != DefaultPublicLimit {
+			values.Set("limit", strconv.Itoa(params.Limit))
+		}
+		if params.Cursor != "" {
+			values.Set("cursor", params.Cursor)
+		}
+
+		if len(values) == 0 {
+			return prefix
+		}
+		return prefix + ":" + values.Encode()
+	}
+}
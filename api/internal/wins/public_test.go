@@ -0,0 +1,34 @@
+package wins
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicCacheKey(t *testing.T) {
+	key := PublicCacheKey("wins")
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"no parameters", "", "wins"},
+		{"defaults are omitted", "?sort=date&limit=20", "wins"},
+		{"placements are sorted and deduplicated", "?placement=second,first,second", "wins:placement=first%2Csecond"},
+		{"search is normalized", "?search=%20Team%20%20Alpha", "wins:search=team+alpha"},
+		{"integers are canonical", "?year=02024&hackathon_id=007", "wins:hackathon_id=7&year=2024"},
+		{"limit out of range", "?limit=1001", "wins:invalid"},
+		{"unparseable year", "?year=x", "wins:invalid"},
+		{"unknown placement", "?placement=best", "wins:invalid"},
+		{"invalid cursor", "?cursor=abc", "wins:invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := key(httptest.NewRequest("GET", "/api/public/wins"+tt.query, nil)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
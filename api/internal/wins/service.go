@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/itam-misis/itam-api/internal/audit"
@@ -27,6 +28,40 @@ func NewService(db *pgxpool.Pool, auditService *audit.Service) *Service {
 	}
 }
 
+// winColumns selects a win with its hackathon name and participants; used with winTables.
+// participantsFilter narrows the participants subquery
+func winColumns(participantsFilter string) string {
+	return `w.id, w.team_name, w.hackathon_id, h.name, w.result, w.prize, w.currency, w.award_date, w.year, w.link, w.sort_order,
+	w.placement, w.rank, w.placement_manual,
+	COALESCE((
+		SELECT json_agg(json_build_object('id', tm.id, 'name', tm.name, 'photo', tm.photo) ORDER BY wp.sort_order, tm.name)
+		FROM win_participants wp JOIN team_members tm ON tm.id = wp.team_member_id
+		WHERE wp.win_id = w.id` + participantsFilter + `
+	), '[]'), w.created_at, w.updated_at`
+}
+
+// publicParticipants leaves hidden team members out of the participants on public pages
+const publicParticipants = " AND tm.is_visible = true"
+
+const winTables = "wins w JOIN hackathons h ON h.id = w.hackathon_id"
+
+// scanWin scans winColumns into w and any columns selected after them into extra
+func scanWin(row pgx.Row, w *Win, extra ...any) error {
+	dest := []any{
+		&w.ID, &w.TeamName, &w.HackathonID, &w.HackathonName, &w.Result, &w.Prize, &w.Currency,
+		&w.AwardDate, &w.Year, &w.Link, &w.SortOrder,
+		&w.Placement, &w.Rank, &w.PlacementManual, &w.Participants, &w.CreatedAt, &w.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// querier is implemented by both the pool and transactions
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // List returns a paginated list of wins
 func (s *Service) List(ctx context.Context, params ListWinsParams) (*ListWinsResponse, error) {
 	if params.Page < 1 {
@@ -39,21 +74,8 @@ func (s *Service) List(ctx context.Context, params ListWinsParams) (*ListWinsRes
 	offset := (params.Page - 1) * params.PageSize
 
 	// Build query
-	baseQuery := "FROM wins WHERE 1=1"
-	var args []any
-	argNum := 1
-
-	if params.Search != "" {
-		baseQuery += fmt.Sprintf(" AND (team_name ILIKE $%d OR hackathon_name ILIKE $%d)", argNum, argNum)
-		args = append(args, "%"+params.Search+"%")
-		argNum++
-	}
-
-	if params.Year != 0 {
-		baseQuery += fmt.Sprintf(" AND year = $%d", argNum)
-		args = append(args, params.Year)
-		argNum++
-	}
+	baseQuery, args := filterQuery(params)
+	argNum := len(args) + 1
 
 	// Get total count
 	var total int
@@ -64,11 +86,11 @@ func (s *Service) List(ctx context.Context, params ListWinsParams) (*ListWinsRes
 
 	// Get wins
 	selectQuery := fmt.Sprintf(`
-		SELECT id, team_name, hackathon_name, result, prize, award_date, year, link, sort_order, created_at, updated_at 
-		%s 
-		ORDER BY year DESC, sort_order DESC, award_date DESC NULLS LAST
+		SELECT %s
+		%s
+		ORDER BY %s
 		LIMIT $%d OFFSET $%d
-	`, baseQuery, argNum, argNum+1)
+	`, winColumns(""), baseQuery, orderBy(params.Sort), argNum, argNum+1)
 	args = append(args, params.PageSize, offset)
 
 	rows, err := s.db.Query(ctx, selectQuery, args...)
@@ -80,10 +102,7 @@ func (s *Service) List(ctx context.Context, params ListWinsParams) (*ListWinsRes
 	var wins []Win
 	for rows.Next() {
 		var w Win
-		if err := rows.Scan(
-			&w.ID, &w.TeamName, &w.HackathonName, &w.Result, &w.Prize,
-			&w.AwardDate, &w.Year, &w.Link, &w.SortOrder, &w.CreatedAt, &w.UpdatedAt,
-		); err != nil {
+		if err := scanWin(rows, &w); err != nil {
 			return nil, fmt.Errorf("failed to scan win: %w", err)
 		}
 		wins = append(wins, w)
@@ -104,52 +123,61 @@ func (s *Service) List(ctx context.Context, params ListWinsParams) (*ListWinsRes
 	}, nil
 }
 
-// ListPublic returns all wins for public API (sorted, no pagination)
-func (s *Service) ListPublic(ctx context.Context) ([]Win, error) {
-	query := `
-		SELECT id, team_name, hackathon_name, result, prize, award_date, year, link, sort_order, created_at, updated_at 
-		FROM wins
-		ORDER BY year DESC, sort_order DESC, award_date DESC NULLS LAST
-	`
+// filterQuery builds the FROM and WHERE clauses for the filters of params (paging is ignored)
+func filterQuery(params ListWinsParams) (string, []any) {
+	baseQuery := "FROM " + winTables + " WHERE 1=1"
+	var args []any
+	argNum := 1
 
-	rows, err := s.db.Query(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query wins: %w", err)
+	if params.Search != "" {
+		baseQuery += fmt.Sprintf(" AND (w.team_name ILIKE $%d OR h.name ILIKE $%d OR w.result ILIKE $%d)", argNum, argNum, argNum)
+		args = append(args, "%"+params.Search+"%")
+		argNum++
 	}
-	defer rows.Close()
 
-	var wins []Win
-	for rows.Next() {
-		var w Win
-		if err := rows.Scan(
-			&w.ID, &w.TeamName, &w.HackathonName, &w.Result, &w.Prize,
-			&w.AwardDate, &w.Year, &w.Link, &w.SortOrder, &w.CreatedAt, &w.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan win: %w", err)
-		}
-		wins = append(wins, w)
+	if params.Year != 0 {
+		baseQuery += fmt.Sprintf(" AND w.year = $%d", argNum)
+		args = append(args, params.Year)
+		argNum++
 	}
 
-	if wins == nil {
-		wins = []Win{}
+	if params.HackathonID != 0 {
+		baseQuery += fmt.Sprintf(" AND w.hackathon_id = $%d", argNum)
+		args = append(args, params.HackathonID)
+		argNum++
+	}
+
+	if len(params.Placements) > 0 {
+		// Unclassified wins count as other, as in the facets
+		baseQuery += fmt.Sprintf(" AND COALESCE(w.placement, '%s') = ANY($%d)", PlaceOther, argNum)
+		args = append(args, params.Placements)
+		argNum++
+	}
+
+	if params.PrizeMin != nil {
+		baseQuery += fmt.Sprintf(" AND w.prize >= $%d", argNum)
+		args = append(args, *params.PrizeMin)
+		argNum++
+	}
+
+	if params.PrizeMax != nil {
+		baseQuery += fmt.Sprintf(" AND w.prize <= $%d", argNum)
+		args = append(args, *params.PrizeMax)
 	}
 
-	return wins, nil
+	return baseQuery, args
 }
 
 // GetByID returns a win by ID
 func (s *Service) GetByID(ctx context.Context, id int64) (*Win, error) {
 	query := `
-		SELECT id, team_name, hackathon_name, result, prize, award_date, year, link, sort_order, created_at, updated_at
-		FROM wins
-		WHERE id = $1
+		SELECT ` + winColumns("") + `
+		FROM ` + winTables + `
+		WHERE w.id = $1
 	`
 
 	var w Win
-	err := s.db.QueryRow(ctx, query, id).Scan(
-		&w.ID, &w.TeamName, &w.HackathonName, &w.Result, &w.Prize,
-		&w.AwardDate, &w.Year, &w.Link, &w.SortOrder, &w.CreatedAt, &w.UpdatedAt,
-	)
+	err := scanWin(s.db.QueryRow(ctx, query, id), &w)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrWinNotFound
@@ -166,38 +194,84 @@ func (s *Service) Create(ctx context.Context, req *CreateWinRequest, userID int6
 		return nil, err
 	}
 
+	w, err := s.insert(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Audit log
+	s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityWin, &w.ID, w, ipAddress)
+
+	return w, nil
+}
+
+// insert stores a validated win together with its hackathon and participants
+func (s *Service) insert(ctx context.Context, req *CreateWinRequest) (*Win, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := loadClassifier(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := insertWin(ctx, tx, c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// insertWin writes a validated win with its participants and returns its ID.
+// Without a manual placement the result is classified by c.
+func insertWin(ctx context.Context, q querier, c *classifier, req *CreateWinRequest) (int64, error) {
 	// Parse award date
 	var awardDate *time.Time
 	if req.AwardDate != nil && *req.AwardDate != "" {
 		parsed, err := parseDate(*req.AwardDate)
 		if err != nil {
-			return nil, fmt.Errorf("invalid award_date format: %w", err)
+			return 0, fmt.Errorf("invalid award_date format: %w", err)
 		}
 		awardDate = &parsed
 	}
 
+	hackathonID, err := resolveHackathon(ctx, q, req.HackathonID, req.HackathonName, req.Hackathon)
+	if err != nil {
+		return 0, err
+	}
+
+	placement, manual := c.resolvePlacement(req.Result, req.Placement, req.Rank)
+
 	query := `
-		INSERT INTO wins (team_name, hackathon_name, result, prize, award_date, year, link, sort_order)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, team_name, hackathon_name, result, prize, award_date, year, link, sort_order, created_at, updated_at
+		INSERT INTO wins (team_name, hackathon_id, result, prize, currency, award_date, year, link, sort_order,
+			placement, rank, placement_manual)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
 	`
 
-	var w Win
-	err := s.db.QueryRow(ctx, query,
-		req.TeamName, req.HackathonName, req.Result, req.Prize,
+	var id int64
+	err = q.QueryRow(ctx, query,
+		req.TeamName, hackathonID, req.Result, req.Prize, req.Currency,
 		awardDate, req.Year, req.Link, req.SortOrder,
-	).Scan(
-		&w.ID, &w.TeamName, &w.HackathonName, &w.Result, &w.Prize,
-		&w.AwardDate, &w.Year, &w.Link, &w.SortOrder, &w.CreatedAt, &w.UpdatedAt,
-	)
+		placement.Placement, placement.Rank, manual,
+	).Scan(&id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create win: %w", err)
+		return 0, fmt.Errorf("failed to create win: %w", err)
 	}
 
-	// Audit log
-	s.audit.LogAction(ctx, &userID, audit.ActionCreate, audit.EntityWin, &w.ID, w, ipAddress)
+	if err := setParticipants(ctx, q, id, req.ParticipantIDs); err != nil {
+		return 0, err
+	}
 
-	return &w, nil
+	return id, nil
 }
 
 // Update updates a win
@@ -206,12 +280,68 @@ func (s *Service) Update(ctx context.Context, id int64, req *UpdateWinRequest, u
 		return nil, err
 	}
 
+	existing, w, err := s.update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	if w == existing {
+		return w, nil
+	}
+
+	// Audit log with changes
+	changes := map[string]any{
+		"before": existing,
+		"after":  w,
+	}
+	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityWin, &w.ID, changes, ipAddress)
+
+	return w, nil
+}
+
+// update applies a validated request without audit logging and returns the win before and after.
+// Both are the same value when nothing changed.
+func (s *Service) update(ctx context.Context, id int64, req *UpdateWinRequest) (*Win, *Win, error) {
 	// Check if win exists
 	existing, err := s.GetByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := loadClassifier(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed, err := applyUpdate(ctx, tx, c, id, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !changed {
+		return existing, existing, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	w, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	return existing, w, nil
+}
+
+// applyUpdate writes the fields set in req and reports whether there was anything to write.
+// A changed result is classified by c unless the placement was set manually.
+// Returns ErrWinNotFound when the win does not exist.
+func applyUpdate(ctx context.Context, q querier, c *classifier, id int64, req *UpdateWinRequest) (bool, error) {
 	// Build update query
 	setParts := []string{}
 	args := []any{}
@@ -222,9 +352,17 @@ func (s *Service) Update(ctx context.Context, id int64, req *UpdateWinRequest, u
 		args = append(args, *req.TeamName)
 		argNum++
 	}
-	if req.HackathonName != nil {
-		setParts = append(setParts, fmt.Sprintf("hackathon_name = $%d", argNum))
-		args = append(args, *req.HackathonName)
+	if req.HackathonID != nil || req.HackathonName != nil {
+		var name string
+		if req.HackathonName != nil {
+			name = *req.HackathonName
+		}
+		hackathonID, err := resolveHackathon(ctx, q, req.HackathonID, name, nil)
+		if err != nil {
+			return false, err
+		}
+		setParts = append(setParts, fmt.Sprintf("hackathon_id = $%d", argNum))
+		args = append(args, hackathonID)
 		argNum++
 	}
 	if req.Result != nil {
@@ -237,6 +375,11 @@ func (s *Service) Update(ctx context.Context, id int64, req *UpdateWinRequest, u
 		args = append(args, *req.Prize)
 		argNum++
 	}
+	if req.Currency != nil {
+		setParts = append(setParts, fmt.Sprintf("currency = $%d", argNum))
+		args = append(args, *req.Currency)
+		argNum++
+	}
 	if req.AwardDate != nil {
 		if *req.AwardDate == "" {
 			setParts = append(setParts, fmt.Sprintf("award_date = $%d", argNum))
@@ -244,7 +387,7 @@ func (s *Service) Update(ctx context.Context, id int64, req *UpdateWinRequest, u
 		} else {
 			parsed, err := parseDate(*req.AwardDate)
 			if err != nil {
-				return nil, fmt.Errorf("invalid award_date format: %w", err)
+				return false, fmt.Errorf("invalid award_date format: %w", err)
 			}
 			setParts = append(setParts, fmt.Sprintf("award_date = $%d", argNum))
 			args = append(args, parsed)
@@ -270,35 +413,131 @@ func (s *Service) Update(ctx context.Context, id int64, req *UpdateWinRequest, u
 		args = append(args, *req.SortOrder)
 		argNum++
 	}
+	if req.Placement != nil {
+		if *req.Placement == "" {
+			setParts = append(setParts, "placement_manual = false")
+		} else {
+			p := placementWithRank(*req.Placement, req.Rank)
+			setParts = append(setParts, fmt.Sprintf("placement = $%d, rank = $%d, placement_manual = true", argNum, argNum+1))
+			args = append(args, p.Placement, p.Rank)
+			argNum += 2
+		}
+	}
 
-	if len(setParts) == 0 {
-		return existing, nil
+	if len(setParts) == 0 && req.ParticipantIDs == nil {
+		return false, nil
 	}
 
-	args = append(args, id)
-	query := fmt.Sprintf(`
-		UPDATE wins SET %s
-		WHERE id = $%d
-		RETURNING id, team_name, hackathon_name, result, prize, award_date, year, link, sort_order, created_at, updated_at
-	`, strings.Join(setParts, ", "), argNum)
+	if len(setParts) > 0 {
+		args = append(args, id)
+		query := fmt.Sprintf(`UPDATE wins SET %s WHERE id = $%d`, strings.Join(setParts, ", "), argNum)
+		tag, err := q.Exec(ctx, query, args...)
+		if err != nil {
+			return false, fmt.Errorf("failed to update win: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return false, ErrWinNotFound
+		}
+	}
 
-	var w Win
-	err = s.db.QueryRow(ctx, query, args...).Scan(
-		&w.ID, &w.TeamName, &w.HackathonName, &w.Result, &w.Prize,
-		&w.AwardDate, &w.Year, &w.Link, &w.SortOrder, &w.CreatedAt, &w.UpdatedAt,
-	)
+	if req.Result != nil || (req.Placement != nil && *req.Placement == "") {
+		if err := classifyWin(ctx, q, c, id); err != nil {
+			return false, err
+		}
+	}
+
+	if req.ParticipantIDs != nil {
+		if err := setParticipants(ctx, q, id, *req.ParticipantIDs); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// classifyWin classifies the stored result of a win unless its placement was set manually
+func classifyWin(ctx context.Context, q querier, c *classifier, id int64) error {
+	var result string
+	err := q.QueryRow(ctx, "SELECT result FROM wins WHERE id = $1 AND NOT placement_manual", id).Scan(&result)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update win: %w", err)
+		return fmt.Errorf("failed to classify win: %w", err)
 	}
 
-	// Audit log with changes
-	changes := map[string]any{
-		"before": existing,
-		"after":  w,
+	p := c.classify(result)
+	if _, err := q.Exec(ctx, "UPDATE wins SET placement = $1, rank = $2 WHERE id = $3", p.Placement, p.Rank, id); err != nil {
+		return fmt.Errorf("failed to classify win: %w", err)
 	}
-	s.audit.LogAction(ctx, &userID, audit.ActionUpdate, audit.EntityWin, &w.ID, changes, ipAddress)
+	return nil
+}
 
-	return &w, nil
+// resolveHackathon returns the ID of the hackathon given by ID, or matches one by name
+// case-insensitively and creates it with the given details (optional) when there is none
+func resolveHackathon(ctx context.Context, q querier, id *int64, name string, details *HackathonDetails) (int64, error) {
+	if id != nil {
+		var exists bool
+		if err := q.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM hackathons WHERE id = $1)", *id).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, ErrHackathonNotFound
+		}
+		return *id, nil
+	}
+
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return 0, ErrHackathonRequired
+	}
+
+	if details == nil {
+		details = &HackathonDetails{}
+	}
+
+	var hackathonID int64
+	err := q.QueryRow(ctx, `
+		WITH inserted AS (
+			INSERT INTO hackathons (name, organizer, city, is_online, start_date, end_date, url)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT ((LOWER(name))) DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM inserted
+		UNION ALL
+		SELECT id FROM hackathons WHERE LOWER(name) = LOWER($1)
+		LIMIT 1
+	`, name, details.Organizer, details.City, details.IsOnline, details.StartDate, details.EndDate, details.URL).Scan(&hackathonID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve hackathon: %w", err)
+	}
+	return hackathonID, nil
+}
+
+// setParticipants replaces the participants of a win, keeping the given order
+func setParticipants(ctx context.Context, q querier, winID int64, memberIDs []int64) error {
+	if _, err := q.Exec(ctx, "DELETE FROM win_participants WHERE win_id = $1", winID); err != nil {
+		return err
+	}
+
+	seen := map[int64]bool{}
+	for i, memberID := range memberIDs {
+		if seen[memberID] {
+			continue
+		}
+		seen[memberID] = true
+
+		_, err := q.Exec(ctx, "INSERT INTO win_participants (win_id, team_member_id, sort_order) VALUES ($1, $2, $3)", winID, memberID, len(memberIDs)-i)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				return ErrMemberNotFound
+			}
+			return fmt.Errorf("failed to add participant: %w", err)
+		}
+	}
+	return nil
 }
 
 // Delete deletes a win
@@ -358,19 +597,46 @@ func (s *Service) GetStats(ctx context.Context) (map[string]any, error) {
 	currentYear := time.Now().Year()
 
 	// Total wins
-	s.db.QueryRow(ctx, "SELECT COUNT(*) FROM wins").Scan(&totalWins)
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM wins").Scan(&totalWins); err != nil {
+		return nil, fmt.Errorf("failed to count wins: %w", err)
+	}
 
-	// Total prize
-	s.db.QueryRow(ctx, "SELECT COALESCE(SUM(prize), 0) FROM wins").Scan(&totalPrize)
+	// Total prize (in the default currency)
+	if err := s.db.QueryRow(ctx, "SELECT COALESCE(SUM(prize), 0) FROM wins WHERE currency = $1", DefaultCurrency).Scan(&totalPrize); err != nil {
+		return nil, fmt.Errorf("failed to sum prizes: %w", err)
+	}
 
 	// Current year prize
-	s.db.QueryRow(ctx, "SELECT COALESCE(SUM(prize), 0) FROM wins WHERE year = $1", currentYear).Scan(&currentYearPrize)
+	if err := s.db.QueryRow(ctx, "SELECT COALESCE(SUM(prize), 0) FROM wins WHERE year = $1 AND currency = $2", currentYear, DefaultCurrency).Scan(&currentYearPrize); err != nil {
+		return nil, fmt.Errorf("failed to sum current year prizes: %w", err)
+	}
+
+	// Prizes in every currency
+	prizeByCurrency := map[string]int64{}
+	rows, err := s.db.Query(ctx, "SELECT currency, SUM(prize) FROM wins GROUP BY currency")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum prizes by currency: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var currency string
+		var sum int64
+		if err := rows.Scan(&currency, &sum); err != nil {
+			return nil, fmt.Errorf("failed to scan prize sum: %w", err)
+		}
+		prizeByCurrency[currency] = sum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to sum prizes by currency: %w", err)
+	}
 
 	return map[string]any{
 		"total_wins":         totalWins,
 		"total_prize":        totalPrize,
 		"current_year_prize": currentYearPrize,
 		"current_year":       currentYear,
+		"currency":           DefaultCurrency,
+		"prize_by_currency":  prizeByCurrency,
 	}, nil
 }
 
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS update_media_blobs_updated_at ON media_blobs;
+DROP TABLE IF EXISTS media_blobs;
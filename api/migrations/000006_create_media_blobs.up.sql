@@ -0,0 +1,16 @@
+-- Content-addressed upload storage (files are named by SHA-256 of their content)
+CREATE TABLE media_blobs (
+    hash CHAR(64) PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('image', 'svg')),
+    url VARCHAR(500) UNIQUE NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,        -- Total bytes on disk including renditions
+    result JSONB,                          -- Upload result returned again on duplicate uploads
+    ref_count INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TRIGGER update_media_blobs_updated_at 
+    BEFORE UPDATE ON media_blobs 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
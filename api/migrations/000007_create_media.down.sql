@@ -0,0 +1,3 @@
+DROP VIEW IF EXISTS media_references;
+DROP TRIGGER IF EXISTS update_media_updated_at ON media;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,49 @@
+-- Media library (one row per stored upload)
+CREATE TABLE media (
+    id SERIAL PRIMARY KEY,
+    url VARCHAR(500) UNIQUE NOT NULL,
+    file_key VARCHAR(64) NOT NULL,         -- Filename stem shared by all renditions of the upload
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('image', 'svg')),
+    mime VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    width INTEGER,
+    height INTEGER,
+    alt_text VARCHAR(500),
+    uploaded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_media_file_key ON media(file_key);
+CREATE INDEX idx_media_uploaded_by ON media(uploaded_by);
+CREATE INDEX idx_media_created ON media(created_at DESC);
+
+CREATE TRIGGER update_media_updated_at 
+    BEFORE UPDATE ON media 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Every place an entity points at an uploaded file.
+-- file_key is the filename stem, so references to responsive renditions count for their source upload.
+CREATE VIEW media_references AS
+SELECT refs.entity_type, refs.entity_id, refs.field, refs.url,
+       substring(refs.url from '/([0-9a-f]{32,64})[^/]*$') AS file_key
+FROM (
+    SELECT 'project' AS entity_type, id AS entity_id, 'cover_image' AS field, cover_image AS url FROM projects
+    UNION ALL
+    SELECT 'club', id, 'cover_image', cover_image FROM clubs
+    UNION ALL
+    SELECT 'club', club_id, 'image_url', image_url FROM club_images
+    UNION ALL
+    SELECT 'team', id, 'photo', photo FROM team_members
+    UNION ALL
+    SELECT 'partner', id, 'logo_svg', logo_svg FROM partners
+    UNION ALL
+    SELECT 'news', id, 'image', image FROM news
+    UNION ALL
+    SELECT 'blog', id, 'cover_image', cover_image FROM blog_posts
+    UNION ALL
+    SELECT 'blog', bp.id, 'content_html', m[1]
+    FROM blog_posts bp, regexp_matches(bp.content_html, '(/uploads/[A-Za-z0-9/_.-]+)', 'g') AS m
+) refs
+WHERE refs.url LIKE '%/uploads/%';
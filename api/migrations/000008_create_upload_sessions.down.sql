@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS upload_session_chunks;
+DROP TABLE IF EXISTS upload_sessions;
+
+DELETE FROM media WHERE kind = 'video';
+DELETE FROM media_blobs WHERE kind = 'video';
+ALTER TABLE media DROP CONSTRAINT media_kind_check;
+ALTER TABLE media ADD CONSTRAINT media_kind_check CHECK (kind IN ('image', 'svg'));
+ALTER TABLE media_blobs DROP CONSTRAINT media_blobs_kind_check;
+ALTER TABLE media_blobs ADD CONSTRAINT media_blobs_kind_check CHECK (kind IN ('image', 'svg'));
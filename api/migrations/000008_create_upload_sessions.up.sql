@@ -0,0 +1,32 @@
+-- Videos are stored alongside images and SVGs
+ALTER TABLE media_blobs DROP CONSTRAINT media_blobs_kind_check;
+ALTER TABLE media_blobs ADD CONSTRAINT media_blobs_kind_check CHECK (kind IN ('image', 'svg', 'video'));
+ALTER TABLE media DROP CONSTRAINT media_kind_check;
+ALTER TABLE media ADD CONSTRAINT media_kind_check CHECK (kind IN ('image', 'svg', 'video'));
+
+-- Resumable chunked uploads
+CREATE TABLE upload_sessions (
+    id CHAR(32) PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('image', 'video')),
+    content_type VARCHAR(100) NOT NULL,
+    filename VARCHAR(255),
+    size BIGINT NOT NULL,
+    chunk_size INTEGER NOT NULL,
+    total_chunks INTEGER NOT NULL,
+    result JSONB,                          -- Set once the upload is assembled
+    created_by INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_upload_sessions_expires ON upload_sessions(expires_at);
+
+CREATE TABLE upload_session_chunks (
+    session_id CHAR(32) REFERENCES upload_sessions(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    size INTEGER NOT NULL,
+    checksum CHAR(64) NOT NULL,            -- SHA-256 of the chunk
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (session_id, chunk_index)
+);
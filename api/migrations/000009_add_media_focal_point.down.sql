@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_media_derived_from;
+ALTER TABLE media DROP COLUMN IF EXISTS derived_from;
+ALTER TABLE media DROP COLUMN IF EXISTS focal_y;
+ALTER TABLE media DROP COLUMN IF EXISTS focal_x;
@@ -0,0 +1,8 @@
+-- Focal point for smart cropping on the landing site, relative to the image (0..1, 0.5 is the center)
+ALTER TABLE media ADD COLUMN focal_x REAL CHECK (focal_x BETWEEN 0 AND 1);
+ALTER TABLE media ADD COLUMN focal_y REAL CHECK (focal_y BETWEEN 0 AND 1);
+
+-- Image the upload was cropped/rotated from
+ALTER TABLE media ADD COLUMN derived_from INTEGER REFERENCES media(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_media_derived_from ON media(derived_from);
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS win_participants;
+
+ALTER TABLE wins DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE wins ADD COLUMN hackathon_name VARCHAR(255);
+UPDATE wins w SET hackathon_name = h.name FROM hackathons h WHERE h.id = w.hackathon_id;
+ALTER TABLE wins ALTER COLUMN hackathon_name SET NOT NULL;
+DROP INDEX IF EXISTS idx_wins_hackathon;
+ALTER TABLE wins DROP COLUMN hackathon_id;
+
+DROP TRIGGER IF EXISTS update_hackathons_updated_at ON hackathons;
+DROP TABLE IF EXISTS hackathons;
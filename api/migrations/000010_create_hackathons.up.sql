@@ -0,0 +1,48 @@
+-- Hackathons referenced by wins
+CREATE TABLE hackathons (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    organizer VARCHAR(255),
+    city VARCHAR(255),                     -- NULL for online-only events
+    is_online BOOLEAN DEFAULT false,
+    start_date DATE,
+    end_date DATE,
+    url VARCHAR(500),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (end_date IS NULL OR start_date IS NULL OR end_date >= start_date)
+);
+
+-- Names are matched case-insensitively, so CSV imports reuse existing hackathons
+CREATE UNIQUE INDEX idx_hackathons_name ON hackathons(LOWER(name));
+
+CREATE TRIGGER update_hackathons_updated_at
+    BEFORE UPDATE ON hackathons
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Move free-text hackathon names into the table
+INSERT INTO hackathons (name)
+SELECT DISTINCT ON (LOWER(TRIM(hackathon_name))) TRIM(hackathon_name)
+FROM wins
+ORDER BY LOWER(TRIM(hackathon_name)), created_at;
+
+ALTER TABLE wins ADD COLUMN hackathon_id INTEGER REFERENCES hackathons(id) ON DELETE RESTRICT;
+UPDATE wins w SET hackathon_id = h.id FROM hackathons h WHERE LOWER(h.name) = LOWER(TRIM(w.hackathon_name));
+ALTER TABLE wins ALTER COLUMN hackathon_id SET NOT NULL;
+ALTER TABLE wins DROP COLUMN hackathon_name;
+
+CREATE INDEX idx_wins_hackathon ON wins(hackathon_id);
+
+-- Prize currency (ISO 4217), existing prizes are in rubles
+ALTER TABLE wins ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+
+-- Team members who took part in a win
+CREATE TABLE win_participants (
+    win_id INTEGER REFERENCES wins(id) ON DELETE CASCADE,
+    team_member_id INTEGER REFERENCES team_members(id) ON DELETE CASCADE,
+    sort_order INTEGER DEFAULT 0,
+    PRIMARY KEY (win_id, team_member_id)
+);
+
+CREATE INDEX idx_win_participants_member ON win_participants(team_member_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS win_import_previews;
@@ -0,0 +1,12 @@
+-- Dry-run imports of wins, committed later by ID
+CREATE TABLE win_import_previews (
+    id CHAR(32) PRIMARY KEY,
+    mode VARCHAR(10) NOT NULL CHECK (mode IN ('skip', 'update', 'insert')),
+    plan JSONB NOT NULL,                   -- Rows to insert or update
+    result JSONB NOT NULL,                 -- Preview shown to the user
+    created_by INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_win_import_previews_expires ON win_import_previews(expires_at);
@@ -0,0 +1,5 @@
+ALTER TABLE stats
+    DROP COLUMN IF EXISTS override,
+    DROP COLUMN IF EXISTS format,
+    DROP COLUMN IF EXISTS params,
+    DROP COLUMN IF EXISTS source;
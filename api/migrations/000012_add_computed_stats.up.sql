@@ -0,0 +1,15 @@
+-- Stats computed from live data, formatted by a template
+ALTER TABLE stats
+    ADD COLUMN source VARCHAR(50),                      -- Computed when set, e.g. 'wins_count'
+    ADD COLUMN params JSONB NOT NULL DEFAULT '{}',      -- Source parameters: year, current_year, currency
+    ADD COLUMN format VARCHAR(100),                     -- e.g. '~{value|mln} млн', '{value|floor:100|group:.}+'
+    ADD COLUMN override BOOLEAN NOT NULL DEFAULT false; -- value is typed by hand instead of computed
+
+-- Hand-typed figures derived from wins, clubs and partners
+UPDATE stats SET source = 'wins_count', format = '{value}' WHERE key = 'achievements_wins_total';
+UPDATE stats SET source = 'wins_prize_sum', format = '~{value|mln} млн' WHERE key = 'achievements_prize_total';
+UPDATE stats SET source = 'wins_years', format = '{value}' WHERE key = 'achievements_wins_years';
+UPDATE stats SET source = 'wins_count', format = '{value|floor:10}+' WHERE key = 'wins_count';
+UPDATE stats SET source = 'wins_prize_sum', format = '{value|floor:100000|group}+' WHERE key = 'prize_total';
+UPDATE stats SET source = 'clubs_count', format = '{value}' WHERE key = 'about_clubs_count';
+UPDATE stats SET source = 'partners_count', format = '{value|floor:5}+' WHERE key = 'partners_count';
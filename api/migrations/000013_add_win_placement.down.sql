@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_wins_placement;
+ALTER TABLE wins
+    DROP COLUMN IF EXISTS placement_manual,
+    DROP COLUMN IF EXISTS rank,
+    DROP COLUMN IF EXISTS placement;
+
+DROP TABLE IF EXISTS win_result_rules;
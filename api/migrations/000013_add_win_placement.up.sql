@@ -0,0 +1,36 @@
+-- Rules that classify free-text win results into placements, editable by admins.
+-- Patterns are regular expressions (RE2) matched against the result lower-cased,
+-- with ё as е and punctuation replaced by single spaces ("1-е место" → "1 е место").
+CREATE TABLE win_result_rules (
+    id SERIAL PRIMARY KEY,
+    pattern VARCHAR(255) NOT NULL,
+    placement VARCHAR(20) NOT NULL CHECK (placement IN ('first', 'second', 'third', 'place', 'prizewinner', 'special', 'finalist', 'other')),
+    rank INTEGER CHECK (rank > 0),         -- For 'place' the first capture group gives the rank
+    priority INTEGER NOT NULL DEFAULT 0,   -- Higher priorities are tried first
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TRIGGER update_win_result_rules_updated_at
+    BEFORE UPDATE ON win_result_rules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO win_result_rules (pattern, placement, rank, priority) VALUES
+    ('(?:^|\s)(\d+)(?:\s*(?:е|ое|ье|й|ий|ый))?\s*(?:место|степени|st|nd|rd|th|place)(?:\s|$)', 'place', NULL, 100),
+    ('(?:место|place)\s*(\d+)', 'place', NULL, 90),
+    ('гран при|grand prix', 'first', 1, 80),
+    ('первое|победител|winner|first', 'first', 1, 70),
+    ('второе|second', 'second', 2, 70),
+    ('третье|third', 'third', 3, 70),
+    ('призер|призовое|prizewinner|runner up', 'prizewinner', NULL, 60),
+    ('финалист|финал|finalist', 'finalist', NULL, 50),
+    ('спец|номинац|приз|special|nomination|award', 'special', NULL, 40);
+
+-- Placement is NULL until the API classifies the win (existing wins on startup)
+ALTER TABLE wins
+    ADD COLUMN placement VARCHAR(20) CHECK (placement IN ('first', 'second', 'third', 'place', 'prizewinner', 'special', 'finalist', 'other')),
+    ADD COLUMN rank INTEGER CHECK (rank > 0),
+    ADD COLUMN placement_manual BOOLEAN NOT NULL DEFAULT false; -- Set by an admin, kept when rules change
+
+CREATE INDEX idx_wins_placement ON wins(placement, rank);
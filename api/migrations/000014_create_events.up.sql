@@ -0,0 +1,34 @@
+-- Upcoming and past events the community takes part in
+CREATE TABLE events (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    organizer VARCHAR(255),
+    description TEXT,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ,
+    registration_deadline TIMESTAMPTZ,
+    link VARCHAR(500),
+    format VARCHAR(20) NOT NULL DEFAULT 'offline' CHECK (format IN ('online', 'offline', 'hybrid')),
+    location VARCHAR(255),                 -- City or venue of offline and hybrid events
+    club_id INTEGER REFERENCES clubs(id) ON DELETE SET NULL,
+    hackathon_id INTEGER REFERENCES hackathons(id) ON DELETE SET NULL, -- Set when wins are recorded for the event
+    is_visible BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (end_at IS NULL OR end_at >= start_at)
+);
+
+CREATE TABLE event_tags (
+    event_id INTEGER REFERENCES events(id) ON DELETE CASCADE,
+    tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE INDEX idx_events_start ON events(start_at);
+CREATE INDEX idx_events_club ON events(club_id);
+CREATE INDEX idx_event_tags_tag ON event_tags(tag_id);
+
+CREATE TRIGGER update_events_updated_at
+    BEFORE UPDATE ON events
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,36 @@
+-- Restore the view of 000007 before dropping what it reads
+DROP VIEW IF EXISTS media_references;
+CREATE VIEW media_references AS
+SELECT refs.entity_type, refs.entity_id, refs.field, refs.url,
+       substring(refs.url from '/([0-9a-f]{32,64})[^/]*$') AS file_key
+FROM (
+    SELECT 'project' AS entity_type, id AS entity_id, 'cover_image' AS field, cover_image AS url FROM projects
+    UNION ALL
+    SELECT 'club', id, 'cover_image', cover_image FROM clubs
+    UNION ALL
+    SELECT 'club', club_id, 'image_url', image_url FROM club_images
+    UNION ALL
+    SELECT 'team', id, 'photo', photo FROM team_members
+    UNION ALL
+    SELECT 'partner', id, 'logo_svg', logo_svg FROM partners
+    UNION ALL
+    SELECT 'news', id, 'image', image FROM news
+    UNION ALL
+    SELECT 'blog', id, 'cover_image', cover_image FROM blog_posts
+    UNION ALL
+    SELECT 'blog', bp.id, 'content_html', m[1]
+    FROM blog_posts bp, regexp_matches(bp.content_html, '(/uploads/[A-Za-z0-9/_.-]+)', 'g') AS m
+) refs
+WHERE refs.url LIKE '%/uploads/%';
+
+DROP TABLE IF EXISTS project_images;
+DROP TABLE IF EXISTS project_authors;
+
+DROP INDEX IF EXISTS idx_projects_win;
+
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS win_id,
+    DROP COLUMN IF EXISTS demo_url,
+    DROP COLUMN IF EXISTS repo_url,
+    DROP COLUMN IF EXISTS content_html,
+    DROP COLUMN IF EXISTS content_json;
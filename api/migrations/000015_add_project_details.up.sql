@@ -0,0 +1,59 @@
+-- Long-form content in the same format as blog posts, links and the related win
+ALTER TABLE projects
+    ADD COLUMN content_json JSONB,
+    ADD COLUMN content_html TEXT,
+    ADD COLUMN repo_url VARCHAR(500),
+    ADD COLUMN demo_url VARCHAR(500),
+    ADD COLUMN win_id INTEGER REFERENCES wins(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_projects_win ON projects(win_id);
+
+-- Team members who made a project
+CREATE TABLE project_authors (
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    team_member_id INTEGER REFERENCES team_members(id) ON DELETE CASCADE,
+    sort_order INTEGER DEFAULT 0,
+    PRIMARY KEY (project_id, team_member_id)
+);
+
+CREATE INDEX idx_project_authors_member ON project_authors(team_member_id);
+
+-- Gallery images, shown in sort_order
+CREATE TABLE project_images (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    caption VARCHAR(500),
+    sort_order INTEGER DEFAULT 0
+);
+
+CREATE INDEX idx_project_images_project ON project_images(project_id, sort_order);
+
+-- Gallery images and uploads embedded in the content are usages of the media library
+CREATE OR REPLACE VIEW media_references AS
+SELECT refs.entity_type, refs.entity_id, refs.field, refs.url,
+       substring(refs.url from '/([0-9a-f]{32,64})[^/]*$') AS file_key
+FROM (
+    SELECT 'project' AS entity_type, id AS entity_id, 'cover_image' AS field, cover_image AS url FROM projects
+    UNION ALL
+    SELECT 'project', project_id, 'images', url FROM project_images
+    UNION ALL
+    SELECT 'project', p.id, 'content_html', m[1]
+    FROM projects p, regexp_matches(p.content_html, '(/uploads/[A-Za-z0-9/_.-]+)', 'g') AS m
+    UNION ALL
+    SELECT 'club', id, 'cover_image', cover_image FROM clubs
+    UNION ALL
+    SELECT 'club', club_id, 'image_url', image_url FROM club_images
+    UNION ALL
+    SELECT 'team', id, 'photo', photo FROM team_members
+    UNION ALL
+    SELECT 'partner', id, 'logo_svg', logo_svg FROM partners
+    UNION ALL
+    SELECT 'news', id, 'image', image FROM news
+    UNION ALL
+    SELECT 'blog', id, 'cover_image', cover_image FROM blog_posts
+    UNION ALL
+    SELECT 'blog', bp.id, 'content_html', m[1]
+    FROM blog_posts bp, regexp_matches(bp.content_html, '(/uploads/[A-Za-z0-9/_.-]+)', 'g') AS m
+) refs
+WHERE refs.url LIKE '%/uploads/%';
@@ -0,0 +1,6 @@
+-- Merged tags are not restored
+DROP INDEX IF EXISTS idx_tags_slug;
+ALTER TABLE tags DROP COLUMN IF EXISTS slug;
+
+DROP INDEX IF EXISTS idx_tags_name;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
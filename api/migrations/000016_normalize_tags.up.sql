@@ -0,0 +1,54 @@
+-- Tag names become unique case-insensitively, like hackathon names
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+
+-- Trim and collapse whitespace
+UPDATE tags SET name = regexp_replace(TRIM(name), '\s+', ' ', 'g');
+
+-- Merge tags that differ only in case into the oldest one, relinking projects and events
+CREATE TEMP TABLE tag_merges AS
+SELECT t.id AS source_id, k.id AS target_id
+FROM tags t
+JOIN (
+    SELECT DISTINCT ON (LOWER(name)) id, LOWER(name) AS key
+    FROM tags
+    ORDER BY LOWER(name), id
+) k ON k.key = LOWER(t.name)
+WHERE t.id <> k.id;
+
+INSERT INTO project_tags (project_id, tag_id)
+SELECT pt.project_id, m.target_id FROM project_tags pt JOIN tag_merges m ON m.source_id = pt.tag_id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO event_tags (event_id, tag_id)
+SELECT et.event_id, m.target_id FROM event_tags et JOIN tag_merges m ON m.source_id = et.tag_id
+ON CONFLICT DO NOTHING;
+
+DELETE FROM tags WHERE id IN (SELECT source_id FROM tag_merges) OR name = '';
+DROP TABLE tag_merges;
+
+CREATE UNIQUE INDEX idx_tags_name ON tags(LOWER(name));
+
+-- URL slugs, generated the same way as internal/tags.Slug; repeated slugs get a numeric suffix
+ALTER TABLE tags ADD COLUMN slug VARCHAR(255);
+
+UPDATE tags SET slug = s.slug
+FROM (
+    SELECT id, CASE WHEN n > 1 THEN base || '-' || n ELSE base END AS slug
+    FROM (
+        SELECT id, base, ROW_NUMBER() OVER (PARTITION BY base ORDER BY id) AS n
+        FROM (
+            SELECT id, COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(regexp_replace(regexp_replace(replace(
+                translate(
+                    replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(LOWER(name),
+                        '+', '-plus'), '#', '-sharp'),
+                        'ё', 'yo'), 'ж', 'zh'), 'ц', 'ts'), 'ч', 'ch'), 'щ', 'sch'), 'ш', 'sh'), 'ю', 'yu'), 'я', 'ya'),
+                    'абвгдезийклмнопрстуфхыэъь', 'abvgdeziyklmnoprstufhye'),
+                ' ', '-'), '[^a-z0-9-]+', '', 'g'), '-+', '-', 'g'), 200)), ''), 'item') AS base
+            FROM tags
+        ) b
+    ) r
+) s
+WHERE s.id = tags.id;
+
+ALTER TABLE tags ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX idx_tags_slug ON tags(slug);
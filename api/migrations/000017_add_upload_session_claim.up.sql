@@ -0,0 +1,2 @@
+-- Set while a request assembles the session, so the file is not assembled inside a locked transaction
+ALTER TABLE upload_sessions ADD COLUMN assembling_until TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+UPDATE stats SET params = params - 'year' WHERE key = 'achievements_prize_total';
+UPDATE stats SET label = 'Год статистики призовых' WHERE key = 'achievements_prize_year';
@@ -0,0 +1,7 @@
+-- The landing shows achievements_prize_total "за {achievements_prize_year} год": sum the prizes of that year only
+UPDATE stats SET params = params || jsonb_build_object('year', (SELECT value::int FROM stats WHERE key = 'achievements_prize_year'))
+WHERE key = 'achievements_prize_total'
+  AND (SELECT value FROM stats WHERE key = 'achievements_prize_year') ~ '^\d{4}$';
+
+UPDATE stats SET label = 'Год статистики призовых (менять вместе с params.year у achievements_prize_total)'
+WHERE key = 'achievements_prize_year';
@@ -0,0 +1,28 @@
+-- References are recognized by the file key alone, so any UPLOAD_BASE_URL (a CDN, the bucket origin) is counted
+CREATE OR REPLACE VIEW media_references AS
+SELECT refs.entity_type, refs.entity_id, refs.field, refs.url,
+       substring(refs.url from '/([0-9a-f]{32,64})[^/]*$') AS file_key
+FROM (
+    SELECT 'project' AS entity_type, id AS entity_id, 'cover_image' AS field, cover_image AS url FROM projects
+    UNION ALL
+    SELECT 'project', project_id, 'images', url FROM project_images
+    UNION ALL
+    SELECT 'project', p.id, 'content_html', m[1]
+    FROM projects p, regexp_matches(p.content_html, '([^[:space:]"''<>()]*/[0-9a-f]{32,64}[A-Za-z0-9_.-]*)', 'g') AS m
+    UNION ALL
+    SELECT 'club', id, 'cover_image', cover_image FROM clubs
+    UNION ALL
+    SELECT 'club', club_id, 'image_url', image_url FROM club_images
+    UNION ALL
+    SELECT 'team', id, 'photo', photo FROM team_members
+    UNION ALL
+    SELECT 'partner', id, 'logo_svg', logo_svg FROM partners
+    UNION ALL
+    SELECT 'news', id, 'image', image FROM news
+    UNION ALL
+    SELECT 'blog', id, 'cover_image', cover_image FROM blog_posts
+    UNION ALL
+    SELECT 'blog', bp.id, 'content_html', m[1]
+    FROM blog_posts bp, regexp_matches(bp.content_html, '([^[:space:]"''<>()]*/[0-9a-f]{32,64}[A-Za-z0-9_.-]*)', 'g') AS m
+) refs
+WHERE refs.url ~ '/[0-9a-f]{32,64}[^/]*$';
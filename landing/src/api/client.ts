@@ -1,6 +1,7 @@
 import type {
   Win,
   Project,
+  ProjectDetail,
   TeamMember,
   NewsItem,
   Partner,
@@ -8,6 +9,7 @@ import type {
   BlogPost,
   Stats,
   TelegramData,
+  WinsPage,
 } from './types';
 
 // API base URL - can be set via environment variable or defaults to relative path
@@ -50,11 +52,25 @@ class ApiClient {
 
   // Public API endpoints
   async getWins(): Promise<Win[]> {
-    return this.fetch<Win[]>('/api/public/wins');
+    // The endpoint is paginated; load every page
+    const wins: Win[] = [];
+    let cursor: string | null = null;
+    do {
+      const query: string = cursor ? `&cursor=${encodeURIComponent(cursor)}` : '';
+      const page: WinsPage = await this.fetch<WinsPage>(`/api/public/wins?limit=100${query}`);
+      wins.push(...page.wins);
+      cursor = page.next_cursor;
+    } while (cursor);
+    return wins;
   }
 
-  async getProjects(): Promise<Project[]> {
-    return this.fetch<Project[]>('/api/public/projects');
+  async getProjects(tags: string[] = []): Promise<Project[]> {
+    const query = tags.length ? `?tags=${encodeURIComponent(tags.join(','))}` : '';
+    return this.fetch<Project[]>(`/api/public/projects${query}`);
+  }
+
+  async getProjectBySlug(slug: string): Promise<ProjectDetail> {
+    return this.fetch<ProjectDetail>(`/api/public/projects/${slug}`);
   }
 
   async getTeam(): Promise<TeamMember[]> {
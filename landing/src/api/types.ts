@@ -10,16 +10,27 @@ export interface Win {
   year: number;
   link: string | null;
   sort_order: number;
+  placement: string | null;
+  rank: number | null;
   created_at: string;
   updated_at: string;
 }
 
+export interface WinsPage {
+  wins: Win[];
+  total: number;
+  next_cursor: string | null;
+}
+
 export interface Project {
   id: number;
   title: string;
   slug: string;
   description: string | null;
   cover_image: string | null;
+  repo_url: string | null;
+  demo_url: string | null;
+  win_id: number | null;
   tags: string[];
   sort_order: number;
   is_published: boolean;
@@ -27,6 +38,32 @@ export interface Project {
   updated_at: string;
 }
 
+export interface ProjectDetail extends Project {
+  content_json: unknown;
+  content_html: string | null;
+  authors: {
+    id: number;
+    name: string;
+    role: string | null;
+    photo: string | null;
+  }[];
+  gallery: {
+    url: string;
+    caption: string | null;
+  }[];
+  win: {
+    id: number;
+    team_name: string;
+    hackathon_name: string;
+    result: string;
+    placement: string | null;
+    rank: number | null;
+    year: number;
+    award_date: string | null;
+    link: string | null;
+  } | null;
+}
+
 export interface TeamMember {
   id: number;
   name: string;